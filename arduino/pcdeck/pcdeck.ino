@@ -201,6 +201,7 @@ String parseString(String data) {
   // Variables to hold parsed values
   String value1;
   String value2;
+  String value3; // optional message from deej, i.e. a newly selected audio device
 
   // Find the first comma
   int separationIndex1 = data.indexOf('|');
@@ -212,13 +213,21 @@ String parseString(String data) {
 
     value2 = data.substring(separationIndex1 + 1);
 
-    updateDisplay(value1, value2);
+    int separationIndex2 = value2.indexOf('|');
+    if (separationIndex2 != -1) {
+      value3 = value2.substring(separationIndex2 + 1);
+      value2 = value2.substring(0, separationIndex2);
+    }
+
+    value3.trim();
+
+    updateDisplay(value1, value2, value3);
       // You can now use the command and values as needed
     } 
     return value1, value2;
   } 
 
-  void updateDisplay(String dateAndTime, String cpuLoad){
+  void updateDisplay(String dateAndTime, String cpuLoad, String message){
 // Set text size and color
   
   
@@ -230,6 +239,10 @@ String parseString(String data) {
   display.setCursor(0, LINE_1_START);
   display.println(dateAndTime);
 
+  if (message.length() > 0) {
+    display.setCursor(0, LINE_2_START);
+    display.println(message);
+  }
   
   display.setCursor(0, LINE_3_START);
   display.println(cpuLoad);
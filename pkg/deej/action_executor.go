@@ -0,0 +1,165 @@
+package deej
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// actionSettings control how key actions run: for how long, how many at once and which programs they may start
+type actionSettings struct {
+	timeout       time.Duration // 0 lets actions run for as long as they like
+	maxConcurrent int           // 0 places no limit
+
+	// lowercase program names or paths that launch and command actions may run. empty allows any program
+	allowedPrograms []string
+}
+
+const (
+	actionSettingsKeyTimeout         = "timeout"
+	actionSettingsKeyMaxConcurrent   = "max_concurrent"
+	actionSettingsKeyAllowedPrograms = "allowed_programs"
+
+	defaultActionTimeout       = 30 * time.Second
+	defaultMaxConcurrentAction = 4
+)
+
+// actionSettingsFromConfig parses the actions section, keeping the default of any field that's invalid
+func actionSettingsFromConfig(value interface{}) (*actionSettings, error) {
+	settings := &actionSettings{
+		timeout:       defaultActionTimeout,
+		maxConcurrent: defaultMaxConcurrentAction,
+	}
+
+	if value == nil {
+		return settings, nil
+	}
+
+	fields, ok := toStringKeyedMap(value)
+	if !ok {
+		return settings, errors.New("actions must be a set of fields")
+	}
+
+	var errs []string
+
+	timeout, err := parseDurationField(fields, actionSettingsKeyTimeout, defaultActionTimeout)
+	if err != nil || timeout < 0 {
+		errs = append(errs, fmt.Sprintf("invalid timeout %v", fields[actionSettingsKeyTimeout]))
+	} else {
+		settings.timeout = timeout
+	}
+
+	if maxValue, ok := fields[actionSettingsKeyMaxConcurrent]; ok {
+		maxConcurrent, err := strconv.Atoi(fmt.Sprint(maxValue))
+		if err != nil || maxConcurrent < 0 {
+			errs = append(errs, fmt.Sprintf("invalid max_concurrent %v", maxValue))
+		} else {
+			settings.maxConcurrent = maxConcurrent
+		}
+	}
+
+	for _, program := range toStringSlice(fields[actionSettingsKeyAllowedPrograms]) {
+		if program = strings.ToLower(strings.TrimSpace(program)); program != "" {
+			settings.allowedPrograms = append(settings.allowedPrograms, program)
+		}
+	}
+
+	if len(errs) > 0 {
+		return settings, errors.New(strings.Join(errs, "; "))
+	}
+
+	return settings, nil
+}
+
+func (s *actionSettings) String() string {
+	return fmt.Sprintf("<actions: timeout %s, max concurrent: %d, allowed programs: %v>",
+		s.timeout, s.maxConcurrent, s.allowedPrograms)
+}
+
+// checkAllowedProgram returns an error if the allowlist is set and doesn't have the given program,
+// by either its full path or its file name
+func (kc *KeyboardController) checkAllowedProgram(program string) error {
+	allowed := kc.deej.config.Actions.allowedPrograms
+	if len(allowed) == 0 {
+		return nil
+	}
+
+	lowerProgram := strings.ToLower(program)
+	baseName := filepath.Base(lowerProgram)
+
+	for _, allowedProgram := range allowed {
+		if allowedProgram == lowerProgram || allowedProgram == baseName {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%s isn't in allowed_programs", program)
+}
+
+// executeAction runs an action in the background, so slow ones don't hold up serial. it's skipped if an identical
+// action (by id) is still running, or if as many actions as allowed are already running. returns whether it started
+func (kc *KeyboardController) executeAction(ea keyEventAction, id string, run func() error) bool {
+	maxConcurrent := kc.deej.config.Actions.maxConcurrent
+
+	kc.runningActionsLock.Lock()
+
+	if kc.runningActions[id] {
+		kc.runningActionsLock.Unlock()
+		kc.logger.Infow("Skipping action, an identical one is still running",
+			"source", ea.source, "event", ea.event, "action", ea.action)
+
+		return false
+	}
+
+	if maxConcurrent > 0 && len(kc.runningActions) >= maxConcurrent {
+		kc.runningActionsLock.Unlock()
+		kc.logger.Warnw("Skipping action, too many actions are running",
+			"source", ea.source, "event", ea.event, "action", ea.action, "limit", maxConcurrent)
+
+		return false
+	}
+
+	kc.runningActions[id] = true
+	kc.runningActionsLock.Unlock()
+
+	kc.logger.Infow("Running action", "source", ea.source, "event", ea.event, "action", ea.action)
+
+	go func() {
+		start := time.Now()
+		err := run()
+
+		kc.runningActionsLock.Lock()
+		delete(kc.runningActions, id)
+		kc.runningActionsLock.Unlock()
+
+		duration := time.Since(start)
+
+		switch {
+		case err == nil:
+			kc.logger.Infow("Action finished",
+				"source", ea.source, "event", ea.event, "action", ea.action, "duration", duration)
+
+		case errors.Is(err, errMacroCancelled):
+			kc.logger.Infow("Action cancelled",
+				"source", ea.source, "event", ea.event, "action", ea.action, "duration", duration)
+
+		default:
+			exitCode := -1
+
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) {
+				exitCode = exitErr.ExitCode()
+			}
+
+			kc.logger.Warnw("Action failed",
+				"source", ea.source, "event", ea.event, "action", ea.action,
+				"duration", duration, "exitCode", exitCode, "error", err)
+		}
+	}()
+
+	return true
+}
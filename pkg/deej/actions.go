@@ -0,0 +1,262 @@
+package deej
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/thoas/go-funk"
+)
+
+// deejAction is one of deej's own actions (such as moving streams between devices) that can be bound to a key.
+// in the config, it's written as its name followed by its arguments: "move_stream discord.exe headset"
+type deejAction struct {
+	name  string
+	args  []string
+	flags map[string]bool
+}
+
+type deejActionHandler struct {
+	minArgs int
+	maxArgs int      // -1 means any amount
+	flags   []string // optional flags, which can appear anywhere among the arguments
+	usage   string
+
+	run func(d *Deej, action *deejAction) error
+}
+
+const (
+	deejActionMoveStream         = "move_stream"
+	deejActionCycleStream        = "cycle_stream"
+	deejActionSetDefaultSink     = "set_default_sink"
+	deejActionSetDefaultSource   = "set_default_source"
+	deejActionCycleDefaultSink   = "cycle_default_sink"
+	deejActionCycleDefaultSource = "cycle_default_source"
+	deejActionSaveScene          = "save_scene"
+	deejActionRestoreScene       = "restore_scene"
+
+	// moves existing streams along with the default device
+	deejActionFlagMove = "--move"
+)
+
+var deejActionHandlers = map[string]deejActionHandler{
+
+	// move_stream <target> <device>: moves the target's playback streams to an output device
+	deejActionMoveStream: {
+		minArgs: 2,
+		maxArgs: 2,
+		usage:   "move_stream <target> <output device>",
+		run: func(d *Deej, action *deejAction) error {
+			return d.showDevice(d.sessions.moveStreams(action.args[0], action.args[1]))
+		},
+	},
+
+	// cycle_stream <target> [device...]: moves the target's playback streams to the next output device
+	deejActionCycleStream: {
+		minArgs: 1,
+		maxArgs: -1,
+		usage:   "cycle_stream <target> [output device...]",
+		run: func(d *Deej, action *deejAction) error {
+			return d.showDevice(d.sessions.cycleStreams(action.args[0], action.args[1:]))
+		},
+	},
+
+	// set_default_sink <device> [--move]: switches the default output device
+	deejActionSetDefaultSink: {
+		minArgs: 1,
+		maxArgs: 1,
+		flags:   []string{deejActionFlagMove},
+		usage:   "set_default_sink <output device> [--move]",
+		run: func(d *Deej, action *deejAction) error {
+			return d.showDevice(d.sessions.setDefaultDevice(true, action.args[0], action.flags[deejActionFlagMove]))
+		},
+	},
+
+	// set_default_source <device> [--move]: switches the default input device
+	deejActionSetDefaultSource: {
+		minArgs: 1,
+		maxArgs: 1,
+		flags:   []string{deejActionFlagMove},
+		usage:   "set_default_source <input device> [--move]",
+		run: func(d *Deej, action *deejAction) error {
+			return d.showDevice(d.sessions.setDefaultDevice(false, action.args[0], action.flags[deejActionFlagMove]))
+		},
+	},
+
+	// cycle_default_sink [device...] [--move]: switches the default output device to the next one
+	deejActionCycleDefaultSink: {
+		minArgs: 0,
+		maxArgs: -1,
+		flags:   []string{deejActionFlagMove},
+		usage:   "cycle_default_sink [output device...] [--move]",
+		run: func(d *Deej, action *deejAction) error {
+			return d.showDevice(d.sessions.cycleDefaultDevice(true, action.args, action.flags[deejActionFlagMove]))
+		},
+	},
+
+	// cycle_default_source [device...] [--move]: switches the default input device to the next one
+	deejActionCycleDefaultSource: {
+		minArgs: 0,
+		maxArgs: -1,
+		flags:   []string{deejActionFlagMove},
+		usage:   "cycle_default_source [input device...] [--move]",
+		run: func(d *Deej, action *deejAction) error {
+			return d.showDevice(d.sessions.cycleDefaultDevice(false, action.args, action.flags[deejActionFlagMove]))
+		},
+	},
+
+	// save_scene <name>: saves every session's volume and mute state as a scene
+	deejActionSaveScene: {
+		minArgs: 1,
+		maxArgs: 1,
+		usage:   "save_scene <name>",
+		run: func(d *Deej, action *deejAction) error {
+			if err := d.sessions.saveScene(action.args[0]); err != nil {
+				return err
+			}
+
+			d.serial.ShowMessage(fmt.Sprintf("Saved %s", action.args[0]))
+
+			return nil
+		},
+	},
+
+	// restore_scene <name>: restores a saved scene
+	deejActionRestoreScene: {
+		minArgs: 1,
+		maxArgs: 1,
+		usage:   "restore_scene <name>",
+		run: func(d *Deej, action *deejAction) error {
+			if err := d.sessions.restoreScene(action.args[0]); err != nil {
+				return err
+			}
+
+			d.serial.ShowMessage(action.args[0])
+
+			return nil
+		},
+	},
+}
+
+// parseDeejAction parses an action string, making sure the action exists and got the right amount of arguments.
+// arguments containing spaces (like device descriptions) can be wrapped in quotes
+func parseDeejAction(actionString string) (*deejAction, error) {
+	fields, err := splitActionArguments(actionString)
+	if err != nil {
+		return nil, fmt.Errorf("split action %q: %w", actionString, err)
+	}
+
+	if len(fields) == 0 {
+		return nil, errors.New("empty action")
+	}
+
+	action := &deejAction{
+		name:  strings.ToLower(fields[0]),
+		args:  []string{},
+		flags: map[string]bool{},
+	}
+
+	handler, ok := deejActionHandlers[action.name]
+	if !ok {
+		return nil, fmt.Errorf("unknown action %q", action.name)
+	}
+
+	// separate flags from the actual arguments
+	for _, field := range fields[1:] {
+		if !strings.HasPrefix(field, "--") {
+			action.args = append(action.args, field)
+			continue
+		}
+
+		flag := strings.ToLower(field)
+		if !funk.ContainsString(handler.flags, flag) {
+			return nil, fmt.Errorf("unknown flag %s for %s, usage: %s", field, action.name, handler.usage)
+		}
+
+		action.flags[flag] = true
+	}
+
+	if len(action.args) < handler.minArgs || (handler.maxArgs >= 0 && len(action.args) > handler.maxArgs) {
+		return nil, fmt.Errorf("wrong number of arguments for %s, usage: %s", action.name, handler.usage)
+	}
+
+	return action, nil
+}
+
+func (a *deejAction) String() string {
+	fields := append([]string{a.name}, a.args...)
+
+	for flag := range a.flags {
+		fields = append(fields, flag)
+	}
+
+	return strings.Join(fields, " ")
+}
+
+// runDeejAction executes one of deej's own actions
+func (d *Deej) runDeejAction(action *deejAction) error {
+	handler, ok := deejActionHandlers[action.name]
+	if !ok {
+		return fmt.Errorf("unknown action %q", action.name)
+	}
+
+	d.logger.Infow("Running action", "action", action)
+
+	if err := handler.run(d, action); err != nil {
+		d.logger.Warnw("Failed to run action", "action", action, "error", err)
+		return fmt.Errorf("run action %s: %w", action.name, err)
+	}
+
+	return nil
+}
+
+// showDevice shows the device an action has switched to on the deck's display, passing along the action's error
+func (d *Deej) showDevice(device audioDevice, err error) error {
+	if err != nil {
+		return err
+	}
+
+	d.serial.ShowMessage(device.displayName())
+
+	return nil
+}
+
+// splitActionArguments splits a string on whitespace, keeping anything wrapped in single or double quotes together
+func splitActionArguments(s string) ([]string, error) {
+	fields := []string{}
+
+	var current strings.Builder
+	var quote rune
+	inField := false
+
+	for _, r := range s {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			current.WriteRune(r)
+		case r == '"' || r == '\'':
+			quote = r
+			inField = true
+		case r == ' ' || r == '\t':
+			if inField {
+				fields = append(fields, current.String())
+				current.Reset()
+				inField = false
+			}
+		default:
+			current.WriteRune(r)
+			inField = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, errors.New("unterminated quote")
+	}
+
+	if inField {
+		fields = append(fields, current.String())
+	}
+
+	return fields, nil
+}
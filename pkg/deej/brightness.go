@@ -1,37 +1,53 @@
 package deej
 
 import (
-	"fmt"
-	"os/exec"
-	"runtime"
 	"strconv"
 	"strings"
 	"time"
+
+	"go.uber.org/zap"
 )
 
 // BrightnessController handles PC brightness control
 type BrightnessController struct {
+	deej   *Deej
+	logger *zap.SugaredLogger
+
 	automaticBrightness          int // Use 0 for disabled, 1 for enabled
 	photoresistorLeft            int
 	photoresistorRight           int
 	avgPhotoresistor             int           // Average of photoresistor values
 	prevAvgPhotoresistor         int           // Previous average of photoresistor values
 	prevEncoderValue             int           // Previous absolute encoder value
+	encoderSeen                  bool          // Whether prevEncoderValue came from the encoder yet
 	lastButtonPress              int           // Last button press state (0 or 1)
 	photoresistorChangeThreshold int           // Threshold for photoresistor change to trigger brightness adjustment
 	lastBrightnessChangeTime     time.Time     // Last time brightness was changed
 	brightnessChangeDelay        time.Duration // Delay between brightness changes
 }
 
+// brightness is adjusted with these combos, which are expected to be bound to the monitor's brightness
+// (i.e. through a tool like Twinkle Tray or an AutoHotkey script)
+var (
+	brightnessUpCombo   = &KeyCombo{Modifiers: []string{keyModifierAlt}, Key: "pageup"}
+	brightnessDownCombo = &KeyCombo{Modifiers: []string{keyModifierAlt}, Key: "pagedown"}
+)
+
+// how far each of the encoder's notches pans its balance target, where 1 is all the way across
+const encoderBalanceStep = 0.05
+
 // NewBrightnessController initializes a new BrightnessController instance
-func NewBrightnessController() *BrightnessController {
+func NewBrightnessController(deej *Deej, logger *zap.SugaredLogger) *BrightnessController {
 	return &BrightnessController{
+		deej:                         deej,
+		logger:                       logger.Named("brightness"),
 		automaticBrightness:          0, // Initialize to 0 (disabled)
 		photoresistorLeft:            0,
 		photoresistorRight:           0,
 		avgPhotoresistor:             0,
 		prevAvgPhotoresistor:         0,
 		prevEncoderValue:             0,               // Initialize previous encoder value to 0
+		encoderSeen:                  false,           // Set by the first encoder reading
 		lastButtonPress:              0,               // Initialize last button press state to 0 (assuming no button press)
 		photoresistorChangeThreshold: 50,              // Default threshold for photoresistor change
 		lastBrightnessChangeTime:     time.Now(),      // Initialize last change time
@@ -58,16 +74,34 @@ func (bc *BrightnessController) HandleBrightnessInfo(info string) {
 	// Calculate average photoresistor value
 	bc.avgPhotoresistor = (photoresistorLeft + photoresistorRight) / 2
 
+	// the encoder's value is an absolute count, so the first reading only tells where the count starts
+	if !bc.encoderSeen {
+		bc.encoderSeen = true
+		bc.prevEncoderValue = encoderValue
+	}
+
 	// Check if encoder value changed
 	if encoderValue != bc.prevEncoderValue {
-		// Adjust brightness based on encoder value change
-		bc.adjustBrightness(encoderValue)
+		// Pan the encoder's balance target if it has one, or adjust brightness based on encoder value change
+		if target := bc.deej.config.EncoderBalance; target != "" {
+			bc.adjustBalance(target, encoderValue)
+		} else {
+			bc.adjustBrightness(encoderValue)
+		}
 		// Update previous encoder value
 		bc.prevEncoderValue = encoderValue
 	}
 
-	// Toggle automatic brightness control based on button press
-	if buttonPress == 1 {
+	// the encoder's button may be the key layer key instead, in which case it doesn't touch automatic brightness
+	if bc.deej.config.KeyLayers.encoder {
+		if buttonPress != bc.lastButtonPress {
+			bc.deej.serial.keyboardController.layerKeyChanged(buttonPress == 1)
+		}
+
+		bc.lastButtonPress = buttonPress
+
+		// Toggle automatic brightness control based on button press
+	} else if buttonPress == 1 {
 		// Only toggle if lastButtonPress was 0 (indicating no consecutive 1s)
 		if bc.lastButtonPress == 0 {
 			bc.toggleAutomaticBrightness()
@@ -88,22 +122,46 @@ func (bc *BrightnessController) adjustBrightness(encoderValue int) {
 
 	if encoderChange > 0 {
 		for i := 0; i < encoderChange; i++ {
-			bc.sendKeyPress("Alt+PgUp")
+			bc.sendKeyPress(brightnessUpCombo)
 		}
 	} else {
 		for i := 0; i > encoderChange; i-- {
-			bc.sendKeyPress("Alt+PgDown")
+			bc.sendKeyPress(brightnessDownCombo)
 		}
 	}
 }
 
+// adjustBalance pans the target by a step for each notch the encoder turned, right for clockwise.
+// it starts from wherever the target is panned right now, which might've been changed outside of deej
+func (bc *BrightnessController) adjustBalance(target string, encoderValue int) {
+	balance, ok := bc.deej.sessions.targetBalance(target)
+	if !ok {
+		balance = 0.5
+	}
+
+	balance += float32(encoderValue-bc.prevEncoderValue) * encoderBalanceStep
+
+	if balance < 0 {
+		balance = 0
+	} else if balance > 1 {
+		balance = 1
+	}
+
+	if err := bc.deej.sessions.setTargetBalance(target, balance); err != nil {
+		bc.logger.Warnw("Failed to pan encoder target", "target", target, "error", err)
+		return
+	}
+
+	bc.logger.Debugw("Panned encoder target", "target", target, "balance", balance)
+}
+
 func (bc *BrightnessController) toggleAutomaticBrightness() {
 	if bc.automaticBrightness == 0 {
 		bc.automaticBrightness = 1
-		fmt.Println("Automatic brightness control enabled")
+		bc.logger.Info("Automatic brightness control enabled")
 	} else {
 		bc.automaticBrightness = 0
-		fmt.Println("Automatic brightness control disabled")
+		bc.logger.Info("Automatic brightness control disabled")
 	}
 }
 
@@ -114,9 +172,9 @@ func (bc *BrightnessController) adjustBrightnessAutomatically() {
 		if time.Since(bc.lastBrightnessChangeTime) >= bc.brightnessChangeDelay {
 			// If current average is greater than previous, increase brightness
 			if bc.avgPhotoresistor > bc.prevAvgPhotoresistor {
-				bc.sendKeyPress("Alt+PgUp")
+				bc.sendKeyPress(brightnessUpCombo)
 			} else { // Decrease brightness
-				bc.sendKeyPress("Alt+PgDown")
+				bc.sendKeyPress(brightnessDownCombo)
 			}
 
 			// Update previous average and last brightness change time
@@ -126,37 +184,13 @@ func (bc *BrightnessController) adjustBrightnessAutomatically() {
 	}
 }
 
-func (bc *BrightnessController) sendKeyPress(key string) {
-	var cmd *exec.Cmd
-
-	switch runtime.GOOS {
-	case "linux":
-		cmd = exec.Command("xdotool", "key", key)
-	case "windows":
-		// Windows-specific command or script to send key press
-		if key == "Alt+PgUp" {
-			cmd = exec.Command("powershell", "-Command", "$wshell = New-Object -ComObject wscript.shell; $wshell.SendKeys('%{PGUP}')")
-		} else {
-			cmd = exec.Command("powershell", "-Command", "$wshell = New-Object -ComObject wscript.shell; $wshell.SendKeys('%{PGDN}')")
-		}
-	case "darwin":
-		// macOS-specific command or script to send key press
-		if key == "Alt+PgUp" {
-			cmd = exec.Command("osascript", "-e", "tell application \"System Events\" to key code 116 using {option down}") // Option+Page Up
-		} else {
-			cmd = exec.Command("osascript", "-e", "tell application \"System Events\" to key code 121 using {option down}") // Option+Page Down
-		}
-	default:
-		fmt.Printf("Unsupported OS: %s\n", runtime.GOOS)
+func (bc *BrightnessController) sendKeyPress(combo *KeyCombo) {
+	if err := bc.deej.sendKeyCombo(combo); err != nil {
+		bc.logger.Warnw("Failed to send key press", "key", combo, "error", err)
 		return
 	}
 
-	err := cmd.Run()
-	if err != nil {
-		fmt.Printf("Failed to send key press: %v\n", err)
-	} else {
-		fmt.Printf("Generated key press: %s\n", key)
-	}
+	bc.logger.Debugw("Generated key press", "key", combo)
 }
 
 // Helper function to get absolute value
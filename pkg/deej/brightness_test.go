@@ -0,0 +1,60 @@
+package deej
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestBrightnessEncoderBalance(t *testing.T) {
+	logger := zap.NewNop().Sugar()
+
+	d := &Deej{
+		logger: logger,
+		config: &CanonicalConfig{
+			KeyLayers:      disabledKeyLayers(),
+			EncoderBalance: "game.exe",
+		},
+	}
+
+	// panned a little to the left already, outside of deej
+	game := &testBalancedSession{testSession: testSession{key: "game.exe", volume: 1}, balance: 0.3}
+
+	m, err := newSessionMap(d, logger, nil)
+	if err != nil {
+		t.Fatalf("create session map: %v", err)
+	}
+
+	m.m[game.key] = []Session{game}
+	d.sessions = m
+	bc := NewBrightnessController(d, logger)
+
+	steps := []struct {
+		encoderValue int
+		expected     float32
+	}{
+
+		// the first reading is wherever the encoder's count happens to be, not that many notches
+		{37, 0.3},
+		{38, 0.35},
+		{36, 0.25},
+	}
+
+	for stepIdx, step := range steps {
+		bc.HandleBrightnessInfo(fmt.Sprintf("%d|0|0|0", step.encoderValue))
+
+		if math.Abs(float64(game.balance-step.expected)) > 0.001 {
+			t.Fatalf("step %d (encoder at %d): expected balance %.2f, got %.2f",
+				stepIdx, step.encoderValue, step.expected, game.balance)
+		}
+	}
+
+	game.balance = 0.9
+	bc.HandleBrightnessInfo("37|0|0|0")
+
+	if math.Abs(float64(game.balance-0.95)) > 0.001 {
+		t.Errorf("expected a notch right of the balance set outside of deej, got %.2f", game.balance)
+	}
+}
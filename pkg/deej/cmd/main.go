@@ -13,11 +13,13 @@ var (
 	buildType  string
 
 	verbose bool
+	scene   string
 )
 
 func init() {
 	flag.BoolVar(&verbose, "verbose", false, "show verbose logs (useful for debugging serial)")
 	flag.BoolVar(&verbose, "v", false, "shorthand for --verbose")
+	flag.StringVar(&scene, "scene", "", "restore a saved volume scene on startup (this doesn't affect an instance that's already running)")
 	flag.Parse()
 }
 
@@ -59,6 +61,10 @@ func main() {
 		d.SetVersion(versionString)
 	}
 
+	if scene != "" {
+		d.SetInitialScene(scene)
+	}
+
 	// onwards, to glory
 	if err = d.Initialize(); err != nil {
 		named.Fatalw("Failed to initialize deej", "error", err)
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"path"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
@@ -18,6 +19,50 @@ import (
 type CanonicalConfig struct {
 	SliderMapping *sliderMap
 
+	// sessions matching these targets are left alone by everything but targets naming them explicitly
+	IgnoredTargets []string
+
+	// actions bound to each key's events, by key index
+	KeyMapping map[int]*keyBinding
+
+	// the bindings of each named key layer, by layer and key index, and which key switches between them
+	KeyLayerMapping map[string]map[int]*keyBinding
+	KeyLayers       *keyLayers
+
+	// the bindings that apply while an app has focus, by (lowercase) process name and key index
+	AppKeyMapping map[string]map[int]*keyBinding
+
+	// actions bound to pressing several keys together, and how close together their presses need to be
+	KeyChords   []*keyChord
+	ChordWindow time.Duration
+
+	// the volume a slider at 100% sets for a target, by (lowercase) target. values over 1.0 boost it (Linux-only)
+	MaxVolume map[string]float32
+
+	// sessions matching these targets start out at the last volume they had
+	RememberVolume []string
+
+	// the remembered volumes, by session key. these live in the internal config
+	RememberedVolumes map[string]float32
+
+	// whether to put every session back at the volume it had before deej touched it, when deej exits
+	RestoreOnExit bool
+
+	// rules for lowering some apps while others are active
+	DuckingRules []*duckingRule
+
+	// which backend sends hotkeys, or "auto" to pick one
+	KeySender string
+
+	// the target the brightness knob pans left and right instead of changing brightness, if any (Linux-only)
+	EncoderBalance string
+
+	// timeouts, concurrency and allowed programs for running key actions
+	Actions *actionSettings
+
+	// saved volume scenes, by (lowercase) name. these live in the internal config, and are guarded by internalLock
+	Scenes map[string]*scene
+
 	ConnectionInfo struct {
 		COMPort  string
 		BaudRate int
@@ -35,6 +80,9 @@ type CanonicalConfig struct {
 
 	userConfig     *viper.Viper
 	internalConfig *viper.Viper
+
+	// the internal config is written to whenever a scene or remembered volume is saved, which can happen during a reload
+	internalLock sync.Mutex
 }
 
 const (
@@ -49,6 +97,21 @@ const (
 	configType = "yaml"
 
 	configKeySliderMapping       = "slider_mapping"
+	configKeyIgnore              = "ignore"
+	configKeyKeyMapping          = "key_mapping"
+	configKeyMaxVolume           = "max_volume"
+	configKeyScenes              = "scenes"
+	configKeyDucking             = "ducking"
+	configKeyRememberVolume      = "remember_volume"
+	configKeyRememberedVolumes   = "volumes"
+	configKeyRestoreOnExit       = "restore_on_exit"
+	configKeyKeySender           = "key_sender"
+	configKeyKeyLayers           = "key_layers"
+	configKeyKeyChords           = "key_chords"
+	configKeyAppKeyMapping       = "app_key_mapping"
+	configKeyChordWindow         = "chord_window"
+	configKeyActions             = "actions"
+	configKeyEncoderBalance      = "encoder_balance"
 	configKeyInvertSliders       = "invert_sliders"
 	configKeyCOMPort             = "com_port"
 	configKeyBaudRate            = "baud_rate"
@@ -85,7 +148,19 @@ func NewConfig(logger *zap.SugaredLogger, notifier Notifier) (*CanonicalConfig,
 	userConfig.SetConfigType(configType)
 	userConfig.AddConfigPath(userConfigPath)
 
-	userConfig.SetDefault(configKeySliderMapping, map[string][]string{})
+	userConfig.SetDefault(configKeySliderMapping, map[string]interface{}{})
+	userConfig.SetDefault(configKeyIgnore, []string{})
+	userConfig.SetDefault(configKeyKeyMapping, map[string]interface{}{})
+	userConfig.SetDefault(configKeyMaxVolume, map[string]interface{}{})
+	userConfig.SetDefault(configKeyDucking, []interface{}{})
+	userConfig.SetDefault(configKeyRememberVolume, []string{})
+	userConfig.SetDefault(configKeyRestoreOnExit, false)
+	userConfig.SetDefault(configKeyKeySender, keySenderAuto)
+	userConfig.SetDefault(configKeyKeyChords, map[string]interface{}{})
+	userConfig.SetDefault(configKeyAppKeyMapping, map[string]interface{}{})
+	userConfig.SetDefault(configKeyChordWindow, defaultChordWindow.String())
+	userConfig.SetDefault(configKeyActions, map[string]interface{}{})
+	userConfig.SetDefault(configKeyEncoderBalance, "")
 	userConfig.SetDefault(configKeyInvertSliders, false)
 	userConfig.SetDefault(configKeyCOMPort, defaultCOMPort)
 	userConfig.SetDefault(configKeyBaudRate, defaultBaudRate)
@@ -131,6 +206,9 @@ func (cc *CanonicalConfig) Load() error {
 		return fmt.Errorf("read user config: %w", err)
 	}
 
+	cc.internalLock.Lock()
+	defer cc.internalLock.Unlock()
+
 	// load the internal config - this doesn't have to exist, so it can error
 	if err := cc.internalConfig.ReadInConfig(); err != nil {
 		cc.logger.Debugw("Viper failed to read internal config", "error", err, "reminder", "this is fine")
@@ -145,12 +223,44 @@ func (cc *CanonicalConfig) Load() error {
 	cc.logger.Info("Loaded config successfully")
 	cc.logger.Infow("Config values",
 		"sliderMapping", cc.SliderMapping,
+		"ignoredTargets", cc.IgnoredTargets,
+		"keyMapping", cc.KeyMapping,
+		"keyLayerMapping", cc.KeyLayerMapping,
+		"keyLayers", cc.KeyLayers,
+		"appKeyMapping", cc.AppKeyMapping,
+		"keyChords", cc.KeyChords,
+		"chordWindow", cc.ChordWindow,
+		"maxVolume", cc.MaxVolume,
+		"duckingRules", cc.DuckingRules,
+		"rememberVolume", cc.RememberVolume,
+		"restoreOnExit", cc.RestoreOnExit,
+		"keySender", cc.KeySender,
+		"actions", cc.Actions,
+		"encoderBalance", cc.EncoderBalance,
 		"connectionInfo", cc.ConnectionInfo,
 		"invertSliders", cc.InvertSliders)
 
 	return nil
 }
 
+// writeInternalConfig saves the internal config (logs/preferences.yaml) to disk. assumes internalLock is held
+func (cc *CanonicalConfig) writeInternalConfig() error {
+	if err := util.EnsureDirExists(internalConfigPath); err != nil {
+		return fmt.Errorf("ensure internal config directory exists: %w", err)
+	}
+
+	filePath := path.Join(internalConfigPath, internalConfigFilepath)
+
+	if err := cc.internalConfig.WriteConfigAs(filePath); err != nil {
+		cc.logger.Warnw("Failed to write internal config", "path", filePath, "error", err)
+		return fmt.Errorf("write internal config: %w", err)
+	}
+
+	cc.logger.Debugw("Wrote internal config", "path", filePath)
+
+	return nil
+}
+
 // SubscribeToChanges allows external components to receive updates when the config is reloaded
 func (cc *CanonicalConfig) SubscribeToChanges() chan bool {
 	c := make(chan bool)
@@ -218,11 +328,101 @@ func (cc *CanonicalConfig) StopWatchingConfigFile() {
 func (cc *CanonicalConfig) populateFromVipers() error {
 
 	// merge the slider mappings from the user and internal configs
-	cc.SliderMapping = sliderMapFromConfigs(
-		cc.userConfig.GetStringMapStringSlice(configKeySliderMapping),
+	sliderMapping, err := sliderMapFromConfigs(
+		cc.userConfig.GetStringMap(configKeySliderMapping),
 		cc.internalConfig.GetStringMapStringSlice(configKeySliderMapping),
 	)
 
+	if err != nil {
+		cc.logger.Warnw("Ignoring invalid slider settings", "error", err)
+	}
+
+	cc.SliderMapping = sliderMapping
+
+	cc.IgnoredTargets = toStringSlice(cc.userConfig.Get(configKeyIgnore))
+
+	cc.RememberVolume = toStringSlice(cc.userConfig.Get(configKeyRememberVolume))
+	cc.RememberedVolumes = rememberedVolumesFromConfig(cc.internalConfig.Get(configKeyRememberedVolumes))
+
+	duckingRules, err := duckingRulesFromConfig(cc.userConfig.Get(configKeyDucking))
+	if err != nil {
+		cc.logger.Warnw("Ignoring invalid ducking rules", "error", err)
+	}
+
+	cc.DuckingRules = duckingRules
+
+	scenes, err := scenesFromConfig(cc.internalConfig.GetStringMap(configKeyScenes))
+	if err != nil {
+		cc.logger.Warnw("Ignoring invalid saved scenes", "error", err)
+	}
+
+	cc.Scenes = scenes
+
+	// parse key actions up front, so mistakes show up in the logs right away rather than on key press
+	keyMapping, keyLayerMapping, err := keyMappingFromConfig(cc.userConfig.GetStringMap(configKeyKeyMapping))
+	if err != nil {
+		cc.logger.Warnw("Ignoring invalid key mappings", "error", err)
+	}
+
+	cc.KeyMapping = keyMapping
+	cc.KeyLayerMapping = keyLayerMapping
+
+	keyLayers, err := keyLayersFromConfig(cc.userConfig.Get(configKeyKeyLayers), keyLayerMapping)
+	if err != nil {
+		cc.logger.Warnw("Ignoring invalid key layers", "error", err)
+	}
+
+	cc.KeyLayers = keyLayers
+
+	appKeyMapping, err := appKeyMappingFromConfig(cc.userConfig.GetStringMap(configKeyAppKeyMapping))
+	if err != nil {
+		cc.logger.Warnw("Ignoring invalid app key mappings", "error", err)
+	}
+
+	cc.AppKeyMapping = appKeyMapping
+
+	keyChords, err := keyChordsFromConfig(cc.userConfig.GetStringMap(configKeyKeyChords))
+	if err != nil {
+		cc.logger.Warnw("Ignoring invalid key chords", "error", err)
+	}
+
+	cc.KeyChords = keyChords
+
+	chordWindowFields := map[string]interface{}{configKeyChordWindow: cc.userConfig.Get(configKeyChordWindow)}
+
+	cc.ChordWindow, err = parseDurationField(chordWindowFields, configKeyChordWindow, defaultChordWindow)
+	if err != nil || cc.ChordWindow < 0 {
+		cc.logger.Warnw("Invalid chord window specified, using default value",
+			"key", configKeyChordWindow,
+			"invalidValue", cc.userConfig.Get(configKeyChordWindow),
+			"defaultValue", defaultChordWindow)
+
+		cc.ChordWindow = defaultChordWindow
+	}
+
+	cc.MaxVolume = map[string]float32{}
+
+	for target, value := range cc.userConfig.GetStringMap(configKeyMaxVolume) {
+		var limit float64
+
+		switch typedValue := value.(type) {
+		case float64:
+			limit = typedValue
+		case int:
+			limit = float64(typedValue)
+		default:
+			cc.logger.Warnw("Ignoring max volume that isn't a number", "target", target, "value", value)
+			continue
+		}
+
+		if limit <= 0 {
+			cc.logger.Warnw("Ignoring max volume that isn't positive", "target", target, "value", value)
+			continue
+		}
+
+		cc.MaxVolume[target] = float32(limit)
+	}
+
 	// get the rest of the config fields - viper saves us a lot of effort here
 	cc.ConnectionInfo.COMPort = cc.userConfig.GetString(configKeyCOMPort)
 
@@ -237,6 +437,31 @@ func (cc *CanonicalConfig) populateFromVipers() error {
 	}
 
 	cc.InvertSliders = cc.userConfig.GetBool(configKeyInvertSliders)
+	cc.RestoreOnExit = cc.userConfig.GetBool(configKeyRestoreOnExit)
+
+	cc.KeySender = strings.ToLower(cc.userConfig.GetString(configKeyKeySender))
+	if !validKeySender(cc.KeySender) {
+		cc.logger.Warnw("Invalid key sender specified, using default value",
+			"key", configKeyKeySender,
+			"invalidValue", cc.KeySender,
+			"defaultValue", keySenderAuto)
+
+		cc.KeySender = keySenderAuto
+	}
+
+	actions, err := actionSettingsFromConfig(cc.userConfig.Get(configKeyActions))
+	if err != nil {
+		cc.logger.Warnw("Ignoring invalid action settings", "error", err)
+	}
+
+	cc.Actions = actions
+
+	// the knob only ever pans its target, so a "balance:" prefix is taken as read
+	cc.EncoderBalance = strings.TrimSpace(cc.userConfig.GetString(configKeyEncoderBalance))
+	if strings.HasPrefix(strings.ToLower(cc.EncoderBalance), balanceTargetPrefix) {
+		cc.EncoderBalance = cc.EncoderBalance[len(balanceTargetPrefix):]
+	}
+
 	cc.NoiseReductionLevel = cc.userConfig.GetString(configKeyNoiseReductionLevel)
 
 	cc.logger.Debug("Populated config fields from vipers")
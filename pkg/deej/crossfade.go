@@ -0,0 +1,131 @@
+package deej
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// crossfade makes a slider fade between two sets of targets: at the bottom only the left targets are audible,
+// at the top only the right ones, and in the middle both play at the center level
+type crossfade struct {
+	left  []string
+	right []string
+
+	curve  string
+	center float32
+}
+
+const (
+	crossfadeKeyLeft   = "left"
+	crossfadeKeyRight  = "right"
+	crossfadeKeyCurve  = "curve"
+	crossfadeKeyCenter = "center"
+
+	// linear fades keep the sum of both levels constant, which sounds like a dip in the middle.
+	// equal-power fades keep the perceived loudness constant instead (both sides are at ~71% in the middle)
+	crossfadeCurveLinear     = "linear"
+	crossfadeCurveEqualPower = "equal-power"
+)
+
+func parseCrossfade(value interface{}) (*crossfade, error) {
+	fields, ok := toStringKeyedMap(value)
+	if !ok {
+		return nil, errors.New("crossfade must be a set of fields")
+	}
+
+	cf := &crossfade{
+		left:  toStringSlice(fields[crossfadeKeyLeft]),
+		right: toStringSlice(fields[crossfadeKeyRight]),
+		curve: crossfadeCurveEqualPower,
+	}
+
+	if len(cf.left) == 0 || len(cf.right) == 0 {
+		return nil, errors.New("crossfade needs both left and right targets")
+	}
+
+	if curve, ok := fields[crossfadeKeyCurve]; ok {
+		cf.curve = strings.ToLower(fmt.Sprint(curve))
+
+		if cf.curve != crossfadeCurveLinear && cf.curve != crossfadeCurveEqualPower {
+			return nil, fmt.Errorf("unknown crossfade curve %q", cf.curve)
+		}
+	}
+
+	// by default, the center level is wherever the curve puts it
+	cf.center = cf.curveLevel(0.5)
+
+	if center, ok := fields[crossfadeKeyCenter]; ok {
+		parsedCenter, err := strconv.ParseFloat(fmt.Sprint(center), 32)
+		if err != nil || parsedCenter < 0 || parsedCenter > 1 {
+			return nil, fmt.Errorf("crossfade center must be between 0 and 1, got %v", center)
+		}
+
+		cf.center = float32(parsedCenter)
+	}
+
+	return cf, nil
+}
+
+// targets returns both sides' targets, which is what the slider is mapped to
+func (cf *crossfade) targets() []string {
+	return append(append([]string{}, cf.left...), cf.right...)
+}
+
+// level returns the level for one of the crossfade's targets, given the slider's position. targets on neither side
+// (such as ones added to the slider outside of its crossfade) aren't part of it, and get false
+func (cf *crossfade) level(target string, position float32) (float32, bool) {
+
+	// the left side is the mirror image of the right one
+	switch {
+	case containsTarget(cf.left, target):
+		position = 1 - position
+	case !containsTarget(cf.right, target):
+		return 0, false
+	}
+
+	return cf.curveLevel(cf.shapePosition(position)), true
+}
+
+// shapePosition moves the slider's middle to wherever the curve reaches the center level, stretching each half
+// to match. the ends stay where they are, so each side still goes all the way from silent to full
+func (cf *crossfade) shapePosition(position float32) float32 {
+	centerPosition := cf.curvePosition(cf.center)
+
+	if position <= 0.5 {
+		return position * 2 * centerPosition
+	}
+
+	return centerPosition + (position-0.5)*2*(1-centerPosition)
+}
+
+// curveLevel returns the right side's level at the given position, before the center shapes it
+func (cf *crossfade) curveLevel(position float32) float32 {
+	if cf.curve == crossfadeCurveLinear {
+		return position
+	}
+
+	return float32(math.Sin(float64(position) * math.Pi / 2))
+}
+
+// curvePosition is the inverse of curveLevel: the position at which the right side reaches the given level
+func (cf *crossfade) curvePosition(level float32) float32 {
+	if cf.curve == crossfadeCurveLinear {
+		return level
+	}
+
+	return float32(math.Asin(float64(level)) * 2 / math.Pi)
+}
+
+// targets are case-insensitive everywhere else, so they are here too
+func containsTarget(targets []string, target string) bool {
+	for _, candidate := range targets {
+		if strings.EqualFold(candidate, target) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,133 @@
+package deej
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseCrossfade(t *testing.T) {
+	cf, err := parseCrossfade(map[string]interface{}{
+		"left":  "spotify.exe",
+		"right": []interface{}{"discord.exe", "teams.exe"},
+	})
+	if err != nil {
+		t.Fatalf("parse crossfade: %v", err)
+	}
+
+	if cf.curve != crossfadeCurveEqualPower || len(cf.left) != 1 || len(cf.right) != 2 {
+		t.Errorf("expected an equal-power crossfade with 1 left and 2 right targets, got %+v", cf)
+	}
+
+	// without a center, the middle is wherever the curve puts it
+	if math.Abs(float64(cf.center)-math.Sqrt2/2) > 0.001 {
+		t.Errorf("expected the center to be at ~0.71, got %.3f", cf.center)
+	}
+
+	cf, err = parseCrossfade(map[interface{}]interface{}{
+		"left":   "spotify.exe",
+		"right":  "discord.exe",
+		"curve":  "Linear",
+		"center": 0.8,
+	})
+	if err != nil {
+		t.Fatalf("parse crossfade: %v", err)
+	}
+
+	if cf.curve != crossfadeCurveLinear || cf.center != 0.8 {
+		t.Errorf("expected a linear crossfade centered at 0.8, got %+v", cf)
+	}
+
+	invalid := []interface{}{
+		"spotify.exe",
+		map[string]interface{}{"left": "spotify.exe"},
+		map[string]interface{}{"left": "spotify.exe", "right": "discord.exe", "curve": "exponential"},
+		map[string]interface{}{"left": "spotify.exe", "right": "discord.exe", "center": 1.5},
+		map[string]interface{}{"left": "spotify.exe", "right": "discord.exe", "center": "loud"},
+	}
+
+	for _, value := range invalid {
+		if cf, err := parseCrossfade(value); err == nil {
+			t.Errorf("expected %v to be invalid, got %+v", value, cf)
+		}
+	}
+}
+
+func TestCrossfadeLevel(t *testing.T) {
+	cf, err := parseCrossfade(map[string]interface{}{
+		"left":   "spotify.exe",
+		"right":  "Discord.exe",
+		"curve":  "linear",
+		"center": 0.8,
+	})
+	if err != nil {
+		t.Fatalf("parse crossfade: %v", err)
+	}
+
+	tests := []struct {
+		target   string
+		position float32
+		expected float32
+	}{
+		{"spotify.exe", 0, 1},
+		{"spotify.exe", 0.5, 0.8},
+		{"spotify.exe", 1, 0},
+		{"discord.exe", 0, 0},
+		{"discord.exe", 0.5, 0.8},
+		{"discord.exe", 0.25, 0.4},
+
+		{"discord.exe", 1, 1},
+	}
+
+	for _, test := range tests {
+		level, ok := cf.level(test.target, test.position)
+		if !ok || math.Abs(float64(level-test.expected)) > 0.001 {
+			t.Errorf("%s at %.2f: expected level %.2f, got %.2f (ok: %v)",
+				test.target, test.position, test.expected, level, ok)
+		}
+	}
+
+	// a center below the curve's own middle only lowers the middle, the ends stay silent and full
+	quietCenters := []struct {
+		curve    string
+		center   float32
+		position float32
+		expected float32
+	}{
+		{crossfadeCurveLinear, 0.3, 0, 0},
+		{crossfadeCurveLinear, 0.3, 0.25, 0.15},
+		{crossfadeCurveLinear, 0.3, 0.5, 0.3},
+		{crossfadeCurveLinear, 0.3, 0.75, 0.65},
+		{crossfadeCurveLinear, 0.3, 1, 1},
+		{crossfadeCurveEqualPower, 0.5, 0, 0},
+		{crossfadeCurveEqualPower, 0.5, 0.5, 0.5},
+		{crossfadeCurveEqualPower, 0.5, 0.75, 0.866},
+		{crossfadeCurveEqualPower, 0.5, 1, 1},
+		{crossfadeCurveLinear, 0, 0.5, 0},
+		{crossfadeCurveLinear, 0, 1, 1},
+	}
+
+	for _, test := range quietCenters {
+		cf, err := parseCrossfade(map[string]interface{}{
+			"left":   "spotify.exe",
+			"right":  "discord.exe",
+			"curve":  test.curve,
+			"center": test.center,
+		})
+		if err != nil {
+			t.Fatalf("parse crossfade: %v", err)
+		}
+
+		right, _ := cf.level("discord.exe", test.position)
+		left, _ := cf.level("spotify.exe", 1-test.position)
+
+		if math.Abs(float64(right-test.expected)) > 0.001 || math.Abs(float64(left-test.expected)) > 0.001 {
+			t.Errorf("%s centered at %.2f, position %.2f: expected both sides at %.3f, got %.3f and %.3f",
+				test.curve, test.center, test.position, test.expected, right, left)
+		}
+	}
+
+	// targets on neither side aren't part of the crossfade, rather than falling to the right side
+	if level, ok := cf.level("firefox.exe", 1); ok {
+		t.Errorf("expected a target on neither side to be skipped, got level %.2f", level)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"sync"
 
 	"go.uber.org/zap"
 
@@ -26,9 +27,18 @@ type Deej struct {
 	serial   *SerialIO
 	sessions *sessionMap
 
-	stopChannel chan bool
-	version     string
-	verbose     bool
+	stopChannel  chan bool
+	version      string
+	verbose      bool
+	initialScene string
+
+	trayScenes *trayScenes
+
+	// created on first use, see sendKeyCombo
+	keySender      KeySender
+	keySenderName  string
+	keySenderFixed bool // set when the sender came from SetKeySender, rather than the config
+	keySenderLock  sync.Mutex
 }
 
 // NewDeej creates a Deej instance
@@ -120,6 +130,12 @@ func (d *Deej) SetVersion(version string) {
 	d.version = version
 }
 
+// SetInitialScene causes deej to restore a saved scene as it starts, if called before Initialize.
+// the scene is restored once the sliders first report their positions, so it isn't overwritten by them
+func (d *Deej) SetInitialScene(scene string) {
+	d.initialScene = scene
+}
+
 // Verbose returns a boolean indicating whether deej is running in verbose mode
 func (d *Deej) Verbose() bool {
 	return d.verbose
@@ -199,6 +215,10 @@ func (d *Deej) stop() error {
 		return fmt.Errorf("release session map: %w", err)
 	}
 
+	d.keySenderLock.Lock()
+	d.closeKeySender()
+	d.keySenderLock.Unlock()
+
 	d.stopTray()
 
 	// attempt to sync on exit - this won't necessarily work but can't harm
@@ -0,0 +1,388 @@
+package deej
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// duckingRule lowers its targets while any of its trigger sessions is active (i.e. a voice chat is playing
+// or recording) or while its key is held, and brings them back once that's over
+type duckingRule struct {
+	triggers []string
+	key      int // -1 when the rule can't be triggered by a key
+	targets  []string
+
+	// how much ducked targets are lowered by, i.e. 0.7 leaves them at 30% of their level
+	attenuation float32
+
+	// how long it takes to fully duck the targets, and to bring them back up
+	attack  time.Duration
+	release time.Duration
+}
+
+// duckedKey tracks a session key the ducker currently has lowered
+type duckedKey struct {
+	level float32 // the level the key would be at if it weren't ducked
+	gain  float32 // the gain last applied on top of that level
+}
+
+const (
+	duckingRuleKeyTriggers    = "triggers"
+	duckingRuleKeyKey         = "key"
+	duckingRuleKeyTargets     = "targets"
+	duckingRuleKeyAttenuation = "attenuation"
+	duckingRuleKeyAttack      = "attack"
+	duckingRuleKeyRelease     = "release"
+
+	defaultDuckingAttenuation = 0.5
+	defaultDuckingAttack      = 200 * time.Millisecond
+	defaultDuckingRelease     = time.Second
+
+	// how often ducking rules are evaluated. triggers are queried on every tick, so this shouldn't be too eager
+	duckingInterval = 50 * time.Millisecond
+
+	// gains this close to 1 count as not ducked
+	duckingGainEpsilon = 0.001
+)
+
+// duckingRulesFromConfig parses the ducking rules from the user config, skipping any that are malformed
+func duckingRulesFromConfig(value interface{}) ([]*duckingRule, error) {
+	items, ok := value.([]interface{})
+	if !ok {
+		if value == nil {
+			return nil, nil
+		}
+
+		return nil, errors.New("ducking rules must be a list")
+	}
+
+	rules := []*duckingRule{}
+	var errs []error
+
+	for itemIdx, item := range items {
+		rule, err := parseDuckingRule(item)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("rule %d: %w", itemIdx, err))
+			continue
+		}
+
+		rules = append(rules, rule)
+	}
+
+	if len(errs) > 0 {
+		return rules, fmt.Errorf("invalid ducking rules: %v", errs)
+	}
+
+	return rules, nil
+}
+
+func parseDuckingRule(value interface{}) (*duckingRule, error) {
+	fields, ok := toStringKeyedMap(value)
+	if !ok {
+		return nil, errors.New("not a set of fields")
+	}
+
+	rule := &duckingRule{
+		triggers:    toStringSlice(fields[duckingRuleKeyTriggers]),
+		key:         -1,
+		targets:     toStringSlice(fields[duckingRuleKeyTargets]),
+		attenuation: defaultDuckingAttenuation,
+		attack:      defaultDuckingAttack,
+		release:     defaultDuckingRelease,
+	}
+
+	if keyValue, ok := fields[duckingRuleKeyKey]; ok {
+		key, err := strconv.Atoi(fmt.Sprint(keyValue))
+		if err != nil || key < 0 {
+			return nil, fmt.Errorf("invalid key index %v", keyValue)
+		}
+
+		rule.key = key
+	}
+
+	if len(rule.triggers) == 0 && rule.key < 0 {
+		return nil, errors.New("needs triggers, a key or both")
+	}
+
+	if len(rule.targets) == 0 {
+		return nil, errors.New("needs targets")
+	}
+
+	if attenuationValue, ok := fields[duckingRuleKeyAttenuation]; ok {
+		attenuation, err := strconv.ParseFloat(fmt.Sprint(attenuationValue), 32)
+		if err != nil || attenuation < 0 || attenuation > 1 {
+			return nil, fmt.Errorf("attenuation must be between 0 and 1, got %v", attenuationValue)
+		}
+
+		rule.attenuation = float32(attenuation)
+	}
+
+	var err error
+
+	if rule.attack, err = parseDurationField(fields, duckingRuleKeyAttack, defaultDuckingAttack); err != nil {
+		return nil, err
+	}
+
+	if rule.release, err = parseDurationField(fields, duckingRuleKeyRelease, defaultDuckingRelease); err != nil {
+		return nil, err
+	}
+
+	return rule, nil
+}
+
+// durations can be written as "300ms" or "1.5s", plain numbers are taken as milliseconds
+func parseDurationField(fields map[string]interface{}, key string, defaultValue time.Duration) (time.Duration, error) {
+	value, ok := fields[key]
+	if !ok {
+		return defaultValue, nil
+	}
+
+	switch typedValue := value.(type) {
+	case int:
+		return time.Duration(typedValue) * time.Millisecond, nil
+	case float64:
+		return time.Duration(typedValue * float64(time.Millisecond)), nil
+	case string:
+		duration, err := time.ParseDuration(typedValue)
+		if err != nil {
+			return 0, fmt.Errorf("invalid %s: %w", key, err)
+		}
+
+		return duration, nil
+	}
+
+	return 0, fmt.Errorf("invalid %s %v", key, value)
+}
+
+// id tells rules apart by what they do rather than by pointer, so a rule's gain carries over config reloads
+// (which parse every rule anew) instead of jumping back to 1
+func (rule *duckingRule) id() string {
+	return fmt.Sprintf("%s|%d|%s", strings.Join(rule.triggers, ","), rule.key, strings.Join(rule.targets, ","))
+}
+
+func (rule *duckingRule) String() string {
+	return fmt.Sprintf("<ducking: %v -> %v, attenuation: %.2f>", rule.triggers, rule.targets, rule.attenuation)
+}
+
+func (m *sessionMap) setupDucking() {
+	go func() {
+		ticker := time.NewTicker(duckingInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				m.useLock.Lock()
+				m.updateDucking()
+				m.useLock.Unlock()
+			case <-m.stopChannel:
+				return
+			}
+		}
+	}()
+}
+
+// updateDucking moves every rule's gain towards where it should be, and applies the result to the ducked sessions.
+// assumes useLock is held
+func (m *sessionMap) updateDucking() {
+	rules := m.deej.config.DuckingRules
+
+	// nothing to do unless a rule is configured, or sessions are still ducked from before a config reload
+	m.duckingLock.Lock()
+	idle := len(rules) == 0 && len(m.duckedKeys) == 0
+	m.duckingLock.Unlock()
+
+	if idle {
+		return
+	}
+
+	gains := map[*duckingRule]float32{}
+	gainsByID := map[string]float32{}
+
+	for _, rule := range rules {
+		target := float32(1)
+		if m.duckingRuleTriggered(rule) {
+			target = 1 - rule.attenuation
+		}
+
+		gains[rule] = m.rampDuckingGain(rule, target)
+		gainsByID[rule.id()] = gains[rule]
+	}
+
+	m.duckingLock.Lock()
+	m.duckingGains = gainsByID
+	m.duckingLock.Unlock()
+
+	// work out the lowest gain for each session key, as targets of more than one rule follow whichever ducks them most
+	keyGains := map[string]float32{}
+
+	for rule, gain := range gains {
+		if gain >= 1-duckingGainEpsilon {
+			continue
+		}
+
+		for _, target := range rule.targets {
+			for _, session := range m.sessionsForTarget(target) {
+
+				// never duck the sessions doing the triggering (i.e. when they're also part of deej.unmapped)
+				if m.anyTargetMatchesSession(rule.triggers, session) {
+					continue
+				}
+
+				if current, ok := keyGains[session.Key()]; !ok || gain < current {
+					keyGains[session.Key()] = gain
+				}
+			}
+		}
+	}
+
+	m.applyDuckingGains(keyGains)
+}
+
+func (m *sessionMap) duckingRuleTriggered(rule *duckingRule) bool {
+	if rule.key >= 0 && m.deej.serial.keyboardController.keyHeld(rule.key) {
+		return true
+	}
+
+	for _, trigger := range rule.triggers {
+		for _, session := range m.sessionsForTarget(trigger) {
+			if active, ok := session.(activeSession); ok && active.Active() {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// rampDuckingGain moves a rule's gain one tick towards the target, at the pace set by its attack or release time
+func (m *sessionMap) rampDuckingGain(rule *duckingRule, target float32) float32 {
+	m.duckingLock.Lock()
+	gain, ok := m.duckingGains[rule.id()]
+	m.duckingLock.Unlock()
+
+	if !ok {
+		gain = 1
+	}
+
+	duration := rule.release
+	if target < gain {
+		duration = rule.attack
+	}
+
+	// a full swing (from 1 down to 0) takes the whole attack or release time
+	step := float32(1)
+	if duration > 0 {
+		step = float32(duckingInterval) / float32(duration)
+	}
+
+	if gain < target {
+		gain += step
+		if gain > target {
+			gain = target
+		}
+	} else {
+		gain -= step
+		if gain < target {
+			gain = target
+		}
+	}
+
+	return gain
+}
+
+func (m *sessionMap) applyDuckingGains(keyGains map[string]float32) {
+	m.duckingLock.Lock()
+	defer m.duckingLock.Unlock()
+
+	// keys that were ducked before but aren't anymore go back to their own level
+	for key := range m.duckedKeys {
+		if _, ok := keyGains[key]; !ok {
+			keyGains[key] = 1
+		}
+	}
+
+	for key, gain := range keyGains {
+		ducked, ok := m.duckedKeys[key]
+		if !ok {
+			ducked = &duckedKey{
+				level: m.unduckedLevel(key),
+				gain:  1,
+			}
+
+			m.duckedKeys[key] = ducked
+		}
+
+		if gain != ducked.gain {
+			ducked.gain = gain
+
+			sessions, _ := m.get(key)
+			for _, session := range sessions {
+				if err := session.SetVolume(ducked.level * gain); err != nil {
+					m.logger.Debugw("Failed to apply ducking to session", "session", session, "error", err)
+				}
+			}
+		}
+
+		if gain >= 1-duckingGainEpsilon {
+			delete(m.duckedKeys, key)
+		}
+	}
+}
+
+// puts every ducked session key back at the level it'd be at without ducking, and forgets about them.
+// assumes useLock is held
+func (m *sessionMap) restoreDuckedKeys() {
+	m.duckingLock.Lock()
+	defer m.duckingLock.Unlock()
+
+	for key, ducked := range m.duckedKeys {
+		sessions, _ := m.get(key)
+		for _, session := range sessions {
+			if err := session.SetVolume(ducked.level); err != nil {
+				m.logger.Warnw("Failed to restore ducked session", "session", session, "error", err)
+			}
+		}
+	}
+
+	m.logger.Debugw("Restored ducked sessions", "keys", len(m.duckedKeys))
+
+	m.duckedKeys = map[string]*duckedKey{}
+	m.duckingGains = map[string]float32{}
+}
+
+// returns the level a session key would be at without any ducking: wherever its slider put it,
+// or its current volume if no slider moved it yet. assumes duckingLock is held
+func (m *sessionMap) unduckedLevel(key string) float32 {
+	if level, ok := m.sliderLevels[key]; ok {
+		return level
+	}
+
+	sessions, ok := m.get(key)
+	if !ok || len(sessions) == 0 {
+		return 0
+	}
+
+	return sessions[0].GetVolume()
+}
+
+// duckVolume takes the volume a slider wants to set for a session and returns the volume to actually set,
+// which is lower while the session is ducked. the slider's level is kept, so it's restored once ducking ends
+func (m *sessionMap) duckVolume(session Session, volume float32) float32 {
+	m.duckingLock.Lock()
+	defer m.duckingLock.Unlock()
+
+	m.sliderLevels[session.Key()] = volume
+
+	ducked, ok := m.duckedKeys[session.Key()]
+	if !ok {
+		return volume
+	}
+
+	ducked.level = volume
+
+	return volume * ducked.gain
+}
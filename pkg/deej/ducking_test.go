@@ -0,0 +1,45 @@
+package deej
+
+import (
+	"testing"
+)
+
+func TestSessionMapReleaseRestoresDucking(t *testing.T) {
+	m := newTestSessionMap(t, &CanonicalConfig{})
+
+	finder := &testSessionFinder{}
+	m.sessionFinder = finder
+
+	game := &testSession{key: "game.exe", volume: 0.2}
+	music := &testSession{key: "spotify.exe", volume: 0.15}
+	chat := &testSession{key: "discord.exe", volume: 0.9}
+
+	for _, session := range []*testSession{game, music, chat} {
+		m.m[session.key] = []Session{session}
+	}
+
+	// a rule is holding the game and music down while discord is active
+	m.duckedKeys[game.key] = &duckedKey{level: 0.8, gain: 0.25}
+	m.duckedKeys[music.key] = &duckedKey{level: 0.6, gain: 0.25}
+	m.duckingGains["discord.exe|-1|game.exe,spotify.exe"] = 0.25
+
+	if err := m.release(); err != nil {
+		t.Fatalf("release session map: %v", err)
+	}
+
+	if game.volume != 0.8 || music.volume != 0.6 {
+		t.Errorf("expected ducked sessions back at 0.80 and 0.60, got %.2f and %.2f", game.volume, music.volume)
+	}
+
+	if chat.volume != 0.9 {
+		t.Errorf("expected sessions that weren't ducked to stay at 0.90, got %.2f", chat.volume)
+	}
+
+	if len(m.duckedKeys) != 0 || len(m.duckingGains) != 0 {
+		t.Errorf("expected ducking state to be cleared, got %v and %v", m.duckedKeys, m.duckingGains)
+	}
+
+	if !finder.released {
+		t.Error("expected the session finder to be released")
+	}
+}
@@ -0,0 +1,310 @@
+package deej
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/omriharel/deej/pkg/deej/util"
+)
+
+// keyAction is what a macro key does on one of its events. in the config, it's either a plain string (a deej action,
+// i.e. "move_stream discord headset") or a single typed field, such as "hotkey: Ctrl+Shift+Esc"
+type keyAction struct {
+	kind  string
+	value string
+
+	deej  *deejAction // parsed up front for deej actions
+	combo *KeyCombo   // and for hotkeys
+	macro *macro      // and macros, which have no value of their own
+}
+
+const (
+	keyActionHotkey  = "hotkey"  // sends a key combination, i.e. "Ctrl+Shift+Esc"
+	keyActionLaunch  = "launch"  // starts a program, optionally followed by its arguments
+	keyActionURL     = "url"     // opens an http(s) URL in the default browser
+	keyActionCommand = "command" // runs a shell command
+	keyActionDeej    = "deej"    // runs one of deej's own actions
+	keyActionType    = "type"    // types out some text
+	keyActionMacro   = "macro"   // runs a sequence of steps, see macro
+)
+
+var keyActionKinds = []string{
+	keyActionHotkey, keyActionLaunch, keyActionURL, keyActionCommand, keyActionDeej, keyActionType, keyActionMacro,
+}
+
+// keyMappingFromConfig parses the key mapping from the user config, skipping any invalid entries. numbered entries
+// make up the base layer, and named ones are layers of their own (see keyLayers)
+func keyMappingFromConfig(value map[string]interface{}) (map[int]*keyBinding, map[string]map[int]*keyBinding, error) {
+	var errs []string
+
+	mapping := parseKeyBindings(value, "", &errs)
+	layerMapping := map[string]map[int]*keyBinding{}
+
+	for name, layerValue := range value {
+		if _, err := strconv.Atoi(name); err == nil {
+			continue
+		}
+
+		fields, ok := toStringKeyedMap(layerValue)
+		if !ok {
+			errs = append(errs, fmt.Sprintf("layer %s: expected key indexes", name))
+			continue
+		}
+
+		layerMapping[strings.ToLower(name)] = parseKeyBindings(fields, fmt.Sprintf("layer %s, ", name), &errs)
+	}
+
+	if len(errs) > 0 {
+		sort.Strings(errs)
+		return mapping, layerMapping, errors.New(strings.Join(errs, "; "))
+	}
+
+	return mapping, layerMapping, nil
+}
+
+// parseKeyBindings parses the numbered entries of a key mapping (or one of its layers), adding any problems to errs
+func parseKeyBindings(value map[string]interface{}, errPrefix string, errs *[]string) map[int]*keyBinding {
+	mapping := map[int]*keyBinding{}
+
+	for keyIdxString, actionValue := range value {
+		keyIdx, err := strconv.Atoi(keyIdxString)
+		if err != nil {
+			continue
+		}
+
+		if keyIdx < 0 {
+			*errs = append(*errs, fmt.Sprintf("%sinvalid key index %q", errPrefix, keyIdxString))
+			continue
+		}
+
+		binding, err := parseKeyBinding(actionValue)
+		if err != nil {
+			*errs = append(*errs, fmt.Sprintf("%skey %d: %v", errPrefix, keyIdx, err))
+			continue
+		}
+
+		mapping[keyIdx] = binding
+	}
+
+	return mapping
+}
+
+func parseKeyAction(value interface{}) (*keyAction, error) {
+
+	// plain strings are deej actions, which is how key mappings started out
+	if actionString, ok := value.(string); ok {
+		return newKeyAction(keyActionDeej, actionString)
+	}
+
+	fields, ok := toStringKeyedMap(value)
+	if !ok || len(fields) != 1 {
+		return nil, fmt.Errorf("expected an action string or a single one of %s", strings.Join(keyActionKinds, ", "))
+	}
+
+	for kind, actionValue := range fields {
+		kind = strings.ToLower(kind)
+
+		if kind == keyActionMacro {
+			m, err := parseMacro(actionValue)
+			if err != nil {
+				return nil, err
+			}
+
+			return &keyAction{kind: kind, macro: m}, nil
+		}
+
+		return newKeyAction(kind, fmt.Sprint(actionValue))
+	}
+
+	return nil, errors.New("empty action")
+}
+
+func newKeyAction(kind string, value string) (*keyAction, error) {
+	action := &keyAction{
+		kind:  kind,
+		value: strings.TrimSpace(value),
+	}
+
+	// leading and trailing spaces may be part of the text
+	if kind == keyActionType {
+		action.value = value
+	}
+
+	if action.value == "" {
+		return nil, fmt.Errorf("empty %s action", kind)
+	}
+
+	switch kind {
+	case keyActionDeej:
+		deejAction, err := parseDeejAction(action.value)
+		if err != nil {
+			return nil, err
+		}
+
+		action.deej = deejAction
+
+	case keyActionHotkey:
+		combo, err := parseKeyCombo(action.value)
+		if err != nil {
+			return nil, err
+		}
+
+		action.combo = combo
+
+	case keyActionLaunch:
+		if _, err := splitActionArguments(action.value); err != nil {
+			return nil, fmt.Errorf("split launch arguments: %w", err)
+		}
+
+	case keyActionURL:
+
+		// the opener would start whatever any other scheme points at (i.e. file: paths to programs),
+		// going around allowed_programs
+		parsed, err := url.Parse(action.value)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+			return nil, fmt.Errorf("only http and https URLs can be opened, got %q", action.value)
+		}
+
+	case keyActionCommand, keyActionType:
+
+	default:
+		return nil, fmt.Errorf("unknown action type %q, expected one of %s", kind, strings.Join(keyActionKinds, ", "))
+	}
+
+	return action, nil
+}
+
+func (a *keyAction) String() string {
+	if a.deej != nil {
+		return a.deej.String()
+	}
+
+	if a.macro != nil {
+		return a.macro.String()
+	}
+
+	return fmt.Sprintf("%s: %s", a.kind, a.value)
+}
+
+// runKeyAction performs the action and waits for it to finish. external commands are killed once they run past
+// the action timeout (or ctx is cancelled), except for programs launched on Linux, which are only waited on
+// until they start
+func (kc *KeyboardController) runKeyAction(ctx context.Context, action *keyAction) error {
+	var program string
+	var args []string
+	detach := false
+
+	switch action.kind {
+	case keyActionDeej:
+		return kc.deej.runDeejAction(action.deej)
+
+	case keyActionHotkey:
+		return kc.sendKeyPress(action.combo)
+
+	case keyActionType:
+		return kc.typeText(action.value)
+
+	case keyActionMacro:
+		return kc.runMacro(ctx, action.macro)
+
+	case keyActionLaunch:
+		fields, _ := splitActionArguments(action.value)
+
+		if err := kc.checkAllowedProgram(fields[0]); err != nil {
+			return err
+		}
+
+		if util.Linux() {
+			program, args = fields[0], fields[1:]
+			detach = true
+		} else {
+			// Start-Process also takes care of shortcuts (.lnk) and documents
+			command := fmt.Sprintf("Start-Process %s", powershellQuote(fields[0]))
+			if len(fields) > 1 {
+				quotedArgs := make([]string, len(fields)-1)
+				for argIdx, arg := range fields[1:] {
+					quotedArgs[argIdx] = powershellQuote(arg)
+				}
+
+				command += fmt.Sprintf(" -ArgumentList %s", strings.Join(quotedArgs, ","))
+			}
+
+			program, args = "powershell", []string{"-Command", command}
+		}
+
+	case keyActionURL:
+		if util.Linux() {
+			program, args = "xdg-open", []string{action.value}
+		} else {
+			program, args = "powershell", []string{"-Command", fmt.Sprintf("Start-Process %s", powershellQuote(action.value))}
+		}
+
+	case keyActionCommand:
+
+		// a shell would run anything it's handed, so with an allowlist in place commands skip it.
+		// their first word is the program that runs, and the one that has to be allowed
+		if len(kc.deej.config.Actions.allowedPrograms) > 0 {
+			fields, err := splitActionArguments(action.value)
+			if err != nil {
+				return fmt.Errorf("split command: %w", err)
+			}
+
+			if err := kc.checkAllowedProgram(fields[0]); err != nil {
+				return err
+			}
+
+			program, args = fields[0], fields[1:]
+		} else if util.Linux() {
+			program, args = "/bin/sh", []string{"-c", action.value}
+		} else {
+			program, args = "cmd.exe", []string{"/C", action.value}
+		}
+
+	default:
+		return fmt.Errorf("unknown action type %q", action.kind)
+	}
+
+	if detach {
+		cmd := exec.Command(program, args...)
+		if err := cmd.Start(); err != nil {
+			return fmt.Errorf("start %s: %w", program, err)
+		}
+
+		kc.logger.Debugw("Launched program", "program", program, "pid", cmd.Process.Pid)
+
+		go func() {
+			err := cmd.Wait()
+			kc.logger.Infow("Launched program exited", "program", program, "pid", cmd.Process.Pid,
+				"exitCode", cmd.ProcessState.ExitCode(), "error", err)
+		}()
+
+		return nil
+	}
+
+	if timeout := kc.deej.config.Actions.timeout; timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	if err := exec.CommandContext(ctx, program, args...).Run(); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("%s timed out after %s: %w", program, kc.deej.config.Actions.timeout, err)
+		}
+
+		return fmt.Errorf("run %s: %w", program, err)
+	}
+
+	return nil
+}
+
+// powershellQuote wraps a string in single quotes, which powershell doesn't expand anything within
+func powershellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
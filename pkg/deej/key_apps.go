@@ -0,0 +1,57 @@
+package deej
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/omriharel/deej/pkg/deej/util"
+)
+
+// appKeyMappingFromConfig parses the per-app key mappings from the user config, by (lowercase) process name.
+// each app's entries are numbered like the base layer's, and skip any that are invalid
+func appKeyMappingFromConfig(value map[string]interface{}) (map[string]map[int]*keyBinding, error) {
+	mapping := map[string]map[int]*keyBinding{}
+	var errs []string
+
+	for app, appValue := range value {
+		fields, ok := toStringKeyedMap(appValue)
+		if !ok {
+			errs = append(errs, fmt.Sprintf("app %s: expected key indexes", app))
+			continue
+		}
+
+		mapping[strings.ToLower(app)] = parseKeyBindings(fields, fmt.Sprintf("app %s, ", app), &errs)
+	}
+
+	if len(errs) > 0 {
+		sort.Strings(errs)
+		return mapping, errors.New(strings.Join(errs, "; "))
+	}
+
+	return mapping, nil
+}
+
+// foregroundAppMapping returns the key mapping of whichever app has focus, or nil if it has none (or there are
+// no per-app mappings at all). the window's owner comes first, so its mapping wins over any of its child processes'
+func (kc *KeyboardController) foregroundAppMapping() map[int]*keyBinding {
+	appMapping := kc.deej.config.AppKeyMapping
+	if len(appMapping) == 0 {
+		return nil
+	}
+
+	processNames, err := util.GetCurrentWindowProcessNames()
+	if err != nil {
+		kc.logger.Debugw("Failed to get foreground window process names", "error", err)
+		return nil
+	}
+
+	for _, processName := range processNames {
+		if mapping, ok := appMapping[strings.ToLower(processName)]; ok {
+			return mapping
+		}
+	}
+
+	return nil
+}
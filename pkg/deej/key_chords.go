@@ -0,0 +1,181 @@
+package deej
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// keyChord binds an action to pressing several keys together, i.e. "0+5". keys that are part of a chord hold their
+// own events back for the chord window, and drop them altogether when the chord is recognised
+type keyChord struct {
+	keys   []int
+	action *keyAction
+}
+
+// keyUpdate is a key level to feed into a key's state, as of the given time
+type keyUpdate struct {
+	down bool
+	at   time.Time
+}
+
+const defaultChordWindow = 50 * time.Millisecond
+
+// keyChordsFromConfig parses the key chords from the user config, skipping any invalid entries
+func keyChordsFromConfig(value map[string]interface{}) ([]*keyChord, error) {
+	chords := []*keyChord{}
+	var errs []string
+
+	for keysString, actionValue := range value {
+		keys, err := parseChordKeys(keysString)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+
+		action, err := parseKeyAction(actionValue)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("chord %s: %v", keysString, err))
+			continue
+		}
+
+		chords = append(chords, &keyChord{keys: keys, action: action})
+	}
+
+	// bigger chords go first, so 0+1+2 isn't taken for 0+1 when all three go down together
+	sort.Slice(chords, func(i, j int) bool {
+		if len(chords[i].keys) != len(chords[j].keys) {
+			return len(chords[i].keys) > len(chords[j].keys)
+		}
+
+		return chords[i].String() < chords[j].String()
+	})
+
+	if len(errs) > 0 {
+		sort.Strings(errs)
+		return chords, errors.New(strings.Join(errs, "; "))
+	}
+
+	return chords, nil
+}
+
+func parseChordKeys(s string) ([]int, error) {
+	seen := map[int]bool{}
+	keys := []int{}
+
+	for _, part := range strings.Split(s, "+") {
+		key, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || key < 0 {
+			return nil, fmt.Errorf("chord %s: invalid key index %q", s, part)
+		}
+
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+
+	if len(keys) < 2 {
+		return nil, fmt.Errorf("chord %s needs at least two keys", s)
+	}
+
+	sort.Ints(keys)
+
+	return keys, nil
+}
+
+func (c *keyChord) String() string {
+	keys := make([]string, len(c.keys))
+	for keyIdx, key := range c.keys {
+		keys[keyIdx] = strconv.Itoa(key)
+	}
+
+	return strings.Join(keys, "+")
+}
+
+// resolveChords works out what each key's state should see given the keys' physical levels, holding back
+// keys that may still turn out to be part of a chord. returns the updates for each key, and the chords
+// recognised on this line. assumes keyStatesLock is held
+func (kc *KeyboardController) resolveChords(keysDown []bool, now time.Time) ([][]keyUpdate, []*keyChord) {
+	chords := kc.deej.config.KeyChords
+	window := kc.deej.config.ChordWindow
+
+	chordKeys := map[int]bool{}
+	for _, chord := range chords {
+		for _, key := range chord.keys {
+			chordKeys[key] = true
+		}
+	}
+
+	// keys that are part of a chord start out pending when they go down
+	for idx, down := range keysDown {
+		state := kc.keyStates[idx]
+
+		if chordKeys[idx] && down && !state.rawDown && !state.chordSuppressed {
+			state.chordPending = true
+			state.chordPendingSince = now
+		}
+
+		state.rawDown = down
+	}
+
+	// a chord is recognised once all of its keys are pending. its keys then stay quiet until they're released
+	var recognised []*keyChord
+
+	for _, chord := range chords {
+		complete := true
+		for _, key := range chord.keys {
+			if key >= len(keysDown) || !kc.keyStates[key].chordPending {
+				complete = false
+				break
+			}
+		}
+
+		if !complete {
+			continue
+		}
+
+		for _, key := range chord.keys {
+			kc.keyStates[key].chordPending = false
+			kc.keyStates[key].chordSuppressed = true
+		}
+
+		recognised = append(recognised, chord)
+	}
+
+	updates := make([][]keyUpdate, len(keysDown))
+
+	for idx, down := range keysDown {
+		state := kc.keyStates[idx]
+
+		switch {
+		case state.chordSuppressed:
+			if !down {
+				state.chordSuppressed = false
+			}
+
+			updates[idx] = []keyUpdate{{false, now}}
+
+		// released before the window ran out, so it was a tap after all
+		case state.chordPending && !down:
+			state.chordPending = false
+			updates[idx] = []keyUpdate{{true, state.chordPendingSince}, {false, now}}
+
+		// held past the window without the rest of a chord, so it was pressed by itself
+		case state.chordPending && now.Sub(state.chordPendingSince) > window:
+			state.chordPending = false
+			updates[idx] = []keyUpdate{{true, state.chordPendingSince}, {true, now}}
+
+		case state.chordPending:
+			updates[idx] = []keyUpdate{{false, now}}
+
+		default:
+			updates[idx] = []keyUpdate{{down, now}}
+		}
+	}
+
+	return updates, recognised
+}
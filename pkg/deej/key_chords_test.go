@@ -0,0 +1,153 @@
+package deej
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseChordKeys(t *testing.T) {
+	keys, err := parseChordKeys("5 + 0+5")
+	if err != nil {
+		t.Fatalf("parse chord keys: %v", err)
+	}
+
+	if !reflect.DeepEqual(keys, []int{0, 5}) {
+		t.Errorf("expected keys [0 5], got %v", keys)
+	}
+
+	for _, invalid := range []string{"3", "3+3", "0+a", "0+-1", ""} {
+		if _, err := parseChordKeys(invalid); err == nil {
+			t.Errorf("expected chord %q to be invalid", invalid)
+		}
+	}
+}
+
+func TestKeyChordsFromConfig(t *testing.T) {
+	chords, err := keyChordsFromConfig(map[string]interface{}{
+		"0+1":   "save_scene one",
+		"0+1+2": "save_scene two",
+		"3+4":   "save_scene three",
+		"5":     "save_scene four",
+	})
+	if err == nil {
+		t.Error("expected the single-key chord to be reported")
+	}
+
+	// bigger chords come first, so they win over the smaller chords they contain
+	var order []string
+	for _, chord := range chords {
+		order = append(order, chord.String())
+	}
+
+	if expected := []string{"0+1+2", "0+1", "3+4"}; !reflect.DeepEqual(order, expected) {
+		t.Errorf("expected chords %v, got %v", expected, order)
+	}
+}
+
+func TestResolveChords(t *testing.T) {
+	ms := time.Millisecond
+	chord := &keyChord{keys: []int{0, 5}, action: &keyAction{kind: keyActionType, value: "chord"}}
+
+	newController := func() *KeyboardController {
+		kc, _ := newTestKeyboardController(t)
+		kc.deej.config.KeyChords = []*keyChord{chord}
+		kc.deej.config.ChordWindow = 50 * ms
+
+		for len(kc.keyStates) < 6 {
+			kc.keyStates = append(kc.keyStates, &keyState{})
+		}
+
+		return kc
+	}
+
+	// keys 0 and 5 are the chord, key 2 isn't part of any
+	levels := func(down ...int) []bool {
+		keysDown := make([]bool, 6)
+		for _, key := range down {
+			keysDown[key] = true
+		}
+
+		return keysDown
+	}
+
+	start := time.Now()
+
+	t.Run("keys going down together make the chord, and stay quiet until released", func(t *testing.T) {
+		kc := newController()
+
+		updates, chords := kc.resolveChords(levels(0, 2, 5), start)
+		if len(chords) != 1 || chords[0] != chord {
+			t.Fatalf("expected the chord to be recognised, got %v", chords)
+		}
+
+		if updates[0][0].down || updates[5][0].down {
+			t.Errorf("expected the chord's keys to stay up, got %v and %v", updates[0], updates[5])
+		}
+
+		if !updates[2][0].down {
+			t.Errorf("expected key 2 to go down right away, got %v", updates[2])
+		}
+
+		// still held well past the window, and one of them released: neither goes down on its own
+		updates, chords = kc.resolveChords(levels(0, 5), start.Add(500*ms))
+		if len(chords) != 0 || updates[0][0].down || updates[5][0].down {
+			t.Errorf("expected the chord's keys to stay quiet while held, got %v %v %v", chords, updates[0], updates[5])
+		}
+
+		updates, _ = kc.resolveChords(levels(5), start.Add(600*ms))
+		if updates[5][0].down {
+			t.Errorf("expected key 5 to stay quiet until released, got %v", updates[5])
+		}
+
+		// once released, key 0 goes back to being held back on its next press
+		kc.resolveChords(levels(), start.Add(700*ms))
+		updates, _ = kc.resolveChords(levels(0), start.Add(800*ms))
+		if updates[0][0].down || !kc.keyStates[0].chordPending {
+			t.Errorf("expected key 0 to be pending again, got %v", updates[0])
+		}
+	})
+
+	t.Run("second key within the window still makes the chord", func(t *testing.T) {
+		kc := newController()
+
+		kc.resolveChords(levels(0), start)
+		_, chords := kc.resolveChords(levels(0, 5), start.Add(30*ms))
+		if len(chords) != 1 {
+			t.Errorf("expected the chord to be recognised, got %v", chords)
+		}
+	})
+
+	t.Run("key held past the window alone is a press of its own, back-dated to when it went down", func(t *testing.T) {
+		kc := newController()
+
+		updates, _ := kc.resolveChords(levels(0), start)
+		if updates[0][0].down {
+			t.Errorf("expected key 0 to be held back, got %v", updates[0])
+		}
+
+		updates, _ = kc.resolveChords(levels(0), start.Add(60*ms))
+		expected := []keyUpdate{{true, start}, {true, start.Add(60 * ms)}}
+		if !reflect.DeepEqual(updates[0], expected) {
+			t.Errorf("expected updates %v, got %v", expected, updates[0])
+		}
+
+		// too late for the chord now
+		_, chords := kc.resolveChords(levels(0, 5), start.Add(70*ms))
+		if len(chords) != 0 {
+			t.Errorf("expected no chord, got %v", chords)
+		}
+	})
+
+	t.Run("key tapped within the window is a tap of its own", func(t *testing.T) {
+		kc := newController()
+
+		kc.resolveChords(levels(0), start)
+		updates, _ := kc.resolveChords(levels(), start.Add(20*ms))
+
+		expected := []keyUpdate{{true, start}, {false, start.Add(20 * ms)}}
+		if !reflect.DeepEqual(updates[0], expected) {
+			t.Errorf("expected updates %v, got %v", expected, updates[0])
+		}
+	})
+}
@@ -0,0 +1,165 @@
+package deej
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// KeyCombo is a key pressed along with any number of modifiers, i.e. "Ctrl+Shift+Esc" or "VolumeUp".
+// keys and modifiers are kept by their canonical (lowercase) names, as listed in keyNames
+type KeyCombo struct {
+	Modifiers []string
+	Key       string
+}
+
+const (
+	keyModifierCtrl  = "ctrl"
+	keyModifierShift = "shift"
+	keyModifierAlt   = "alt"
+	keyModifierSuper = "super" // the windows key
+)
+
+// keyModifierAliases maps every accepted spelling of a modifier to its canonical name
+var keyModifierAliases = map[string]string{
+	"ctrl": keyModifierCtrl, "control": keyModifierCtrl,
+	"shift": keyModifierShift,
+	"alt":   keyModifierAlt, "option": keyModifierAlt,
+	"super": keyModifierSuper, "win": keyModifierSuper, "windows": keyModifierSuper, "meta": keyModifierSuper,
+	"cmd": keyModifierSuper,
+}
+
+// keyNames holds the canonical names of the keys that aren't a single letter or digit
+var keyNames = []string{
+	"esc", "enter", "tab", "space", "backspace", "delete", "insert", "home", "end", "pageup", "pagedown",
+	"up", "down", "left", "right", "capslock", "numlock", "scrolllock", "printscreen", "pause", "menu",
+	"minus", "equal", "comma", "period", "slash", "backslash", "semicolon", "apostrophe", "grave",
+	"leftbracket", "rightbracket",
+
+	// media keys
+	"volumeup", "volumedown", "mute", "playpause", "nexttrack", "prevtrack", "stop",
+	"brightnessup", "brightnessdown",
+
+	// f1 - f24 are handled separately
+}
+
+// keyNameAliases maps other accepted spellings of a key to its canonical name
+var keyNameAliases = map[string]string{
+	"escape": "esc", "return": "enter", "del": "delete", "ins": "insert", "bksp": "backspace",
+	"pgup": "pageup", "pgdn": "pagedown", "pgdown": "pagedown", "prtsc": "printscreen", "print": "printscreen",
+	"break": "pause", "apps": "menu", "-": "minus", "=": "equal", ",": "comma", ".": "period", "/": "slash",
+	"\\": "backslash", ";": "semicolon", "'": "apostrophe", "`": "grave", "[": "leftbracket", "]": "rightbracket",
+	"volup": "volumeup", "voldown": "volumedown", "volumemute": "mute", "play": "playpause", "next": "nexttrack",
+	"prev": "prevtrack", "previous": "prevtrack", "previoustrack": "prevtrack",
+}
+
+// parseKeyCombo parses a key combination such as "Ctrl+Shift+Esc". names aren't case sensitive,
+// and the key itself always comes last
+func parseKeyCombo(s string) (*KeyCombo, error) {
+	parts := strings.Split(strings.TrimSpace(s), "+")
+
+	combo := &KeyCombo{}
+
+	for _, part := range parts[:len(parts)-1] {
+		modifier, ok := keyModifierAliases[strings.ToLower(strings.TrimSpace(part))]
+		if !ok {
+			return nil, fmt.Errorf("unknown modifier %q in %q", part, s)
+		}
+
+		combo.Modifiers = append(combo.Modifiers, modifier)
+	}
+
+	key, err := canonicalKeyName(parts[len(parts)-1])
+	if err != nil {
+		return nil, fmt.Errorf("%w in %q", err, s)
+	}
+
+	combo.Key = key
+
+	return combo, nil
+}
+
+func canonicalKeyName(name string) (string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+
+	if name == "" {
+		return "", errors.New("missing key")
+	}
+
+	if alias, ok := keyNameAliases[name]; ok {
+		return alias, nil
+	}
+
+	// single letters and digits
+	if len(name) == 1 && (name[0] >= 'a' && name[0] <= 'z' || name[0] >= '0' && name[0] <= '9') {
+		return name, nil
+	}
+
+	if functionKeyNumber(name) > 0 {
+		return name, nil
+	}
+
+	for _, keyName := range keyNames {
+		if name == keyName {
+			return name, nil
+		}
+	}
+
+	return "", fmt.Errorf("unknown key %q", name)
+}
+
+// functionKeyNumber returns the number of a function key name (f1 - f24), or 0 for any other name
+func functionKeyNumber(name string) int {
+	var number int
+	if _, err := fmt.Sscanf(name, "f%d", &number); err != nil || fmt.Sprintf("f%d", number) != name {
+		return 0
+	}
+
+	if number < 1 || number > 24 {
+		return 0
+	}
+
+	return number
+}
+
+func (c *KeyCombo) String() string {
+	parts := make([]string, 0, len(c.Modifiers)+1)
+	for _, modifier := range c.Modifiers {
+		parts = append(parts, strings.Title(modifier))
+	}
+
+	return strings.Join(append(parts, strings.Title(c.Key)), "+")
+}
+
+// keys typing each character takes on a US layout, for the characters that aren't a letter or digit
+var charKeys = map[rune]string{
+	' ': "space", '\n': "enter", '\t': "tab", '-': "minus", '=': "equal", ',': "comma", '.': "period", '/': "slash",
+	'\\': "backslash", ';': "semicolon", '\'': "apostrophe", '`': "grave", '[': "leftbracket", ']': "rightbracket",
+}
+
+// the same for characters typed with shift
+var shiftedCharKeys = map[rune]string{
+	'!': "1", '@': "2", '#': "3", '$': "4", '%': "5", '^': "6", '&': "7", '*': "8", '(': "9", ')': "0",
+	'_': "minus", '+': "equal", '<': "comma", '>': "period", '?': "slash", '|': "backslash", ':': "semicolon",
+	'"': "apostrophe", '~': "grave", '{': "leftbracket", '}': "rightbracket",
+}
+
+// keyComboForChar returns the combo that types a character on a US layout, for senders that can only send keys
+func keyComboForChar(char rune) (*KeyCombo, error) {
+	switch {
+	case char >= 'a' && char <= 'z', char >= '0' && char <= '9':
+		return &KeyCombo{Key: string(char)}, nil
+	case char >= 'A' && char <= 'Z':
+		return &KeyCombo{Modifiers: []string{keyModifierShift}, Key: string(char - 'A' + 'a')}, nil
+	}
+
+	if key, ok := charKeys[char]; ok {
+		return &KeyCombo{Key: key}, nil
+	}
+
+	if key, ok := shiftedCharKeys[char]; ok {
+		return &KeyCombo{Modifiers: []string{keyModifierShift}, Key: key}, nil
+	}
+
+	return nil, fmt.Errorf("can't type %q", char)
+}
@@ -0,0 +1,221 @@
+package deej
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// keyBinding holds the actions bound to each of a key's events, along with the timing that tells them apart
+type keyBinding struct {
+	actions map[string]*keyAction // by event
+
+	// how long a key must be held before its hold action runs, and how often that action repeats after
+	// (0 runs it once per hold)
+	holdDelay time.Duration
+	repeat    time.Duration
+
+	// how soon a second press must follow the first to count as a double tap
+	doubleTapWindow time.Duration
+}
+
+// keyState tracks a single key between the lines read from serial
+type keyState struct {
+	down      bool
+	downSince time.Time
+
+	// set once the current press ran its hold action, and when it last did
+	held       bool
+	lastHoldAt time.Time
+
+	// set when the current press was the second tap of a double tap, which doesn't count as a hold
+	doubleTapped bool
+
+	// set while a press waits to see whether a second tap follows it (only for keys with a double tap action)
+	pendingPress   bool
+	pendingPressAt time.Time
+
+	// the key's physical level, which is what down follows unless the key is (or may be) part of a chord
+	rawDown bool
+
+	// set while the key is held back in case it's part of a chord, and once it was, until it's released
+	chordPending      bool
+	chordPendingSince time.Time
+	chordSuppressed   bool
+}
+
+const (
+	keyEventPress     = "press"      // the key went down
+	keyEventRelease   = "release"    // the key went up
+	keyEventHold      = "hold"       // the key was held down for hold_delay (and every repeat after that)
+	keyEventDoubleTap = "double_tap" // the key was pressed twice within double_tap_window
+
+	keyBindingKeyHoldDelay       = "hold_delay"
+	keyBindingKeyRepeat          = "repeat"
+	keyBindingKeyDoubleTapWindow = "double_tap_window"
+
+	defaultKeyHoldDelay       = 500 * time.Millisecond
+	defaultKeyDoubleTapWindow = 300 * time.Millisecond
+)
+
+var keyEvents = []string{keyEventPress, keyEventRelease, keyEventHold, keyEventDoubleTap}
+
+// parseKeyBinding accepts either a single action (bound to the key's press, which is how key mappings started out)
+// or a set of fields binding actions to any of the key's events
+func parseKeyBinding(value interface{}) (*keyBinding, error) {
+	binding := &keyBinding{
+		actions:         map[string]*keyAction{},
+		holdDelay:       defaultKeyHoldDelay,
+		doubleTapWindow: defaultKeyDoubleTapWindow,
+	}
+
+	fields, ok := toStringKeyedMap(value)
+	if !ok || !isKeyBindingFields(fields) {
+		action, err := parseKeyAction(value)
+		if err != nil {
+			return nil, err
+		}
+
+		binding.actions[keyEventPress] = action
+		return binding, nil
+	}
+
+	for field, fieldValue := range fields {
+		field = strings.ToLower(field)
+
+		switch field {
+		case keyEventPress, keyEventRelease, keyEventHold, keyEventDoubleTap:
+			action, err := parseKeyAction(fieldValue)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", field, err)
+			}
+
+			binding.actions[field] = action
+
+		case keyBindingKeyHoldDelay, keyBindingKeyRepeat, keyBindingKeyDoubleTapWindow:
+
+		default:
+			return nil, fmt.Errorf("unknown field %q, expected any of %s", field, strings.Join(keyEvents, ", "))
+		}
+	}
+
+	if len(binding.actions) == 0 {
+		return nil, errors.New("no actions")
+	}
+
+	var err error
+
+	if binding.holdDelay, err = parseDurationField(fields, keyBindingKeyHoldDelay, defaultKeyHoldDelay); err != nil {
+		return nil, err
+	}
+
+	if binding.repeat, err = parseDurationField(fields, keyBindingKeyRepeat, 0); err != nil {
+		return nil, err
+	}
+
+	if binding.doubleTapWindow, err = parseDurationField(fields, keyBindingKeyDoubleTapWindow,
+		defaultKeyDoubleTapWindow); err != nil {
+		return nil, err
+	}
+
+	if binding.holdDelay < 0 || binding.repeat < 0 || binding.doubleTapWindow < 0 {
+		return nil, errors.New("durations can't be negative")
+	}
+
+	return binding, nil
+}
+
+// returns true if the fields bind actions to events, rather than being a single typed action
+func isKeyBindingFields(fields map[string]interface{}) bool {
+	for field := range fields {
+		switch strings.ToLower(field) {
+		case keyEventPress, keyEventRelease, keyEventHold, keyEventDoubleTap,
+			keyBindingKeyHoldDelay, keyBindingKeyRepeat, keyBindingKeyDoubleTapWindow:
+			return true
+		}
+	}
+
+	return false
+}
+
+func (b *keyBinding) String() string {
+	events := make([]string, 0, len(b.actions))
+	for event, action := range b.actions {
+		events = append(events, fmt.Sprintf("%s: %s", event, action))
+	}
+
+	sort.Strings(events)
+
+	return fmt.Sprintf("<%s>", strings.Join(events, ", "))
+}
+
+// update moves the key's state along given its current level, and returns the events that happened.
+// a press is only reported once it's clear the key wasn't held (for keys with a hold action)
+// or tapped twice (for keys with a double tap action). the firmware reports key levels continuously, so timed events (holds, single taps that turned out not to be
+// double taps) are picked up on whichever line comes after they're due
+func (s *keyState) update(down bool, now time.Time, binding *keyBinding) []string {
+	var events []string
+
+	// the rest only matters for keys that have something bound to them
+	if binding == nil {
+		s.down = down
+		return nil
+	}
+
+	_, hasDoubleTap := binding.actions[keyEventDoubleTap]
+	_, hasHold := binding.actions[keyEventHold]
+
+	// a first tap that wasn't followed by a second one in time is a regular press after all,
+	// unless the key is still down and may turn out to be held instead
+	if s.pendingPress && now.Sub(s.pendingPressAt) > binding.doubleTapWindow && !(hasHold && s.down) {
+		s.pendingPress = false
+		events = append(events, keyEventPress)
+	}
+
+	switch {
+	case down && !s.down:
+		s.down = true
+		s.downSince = now
+		s.held = false
+		s.doubleTapped = false
+
+		if hasDoubleTap {
+			if s.pendingPress && now.Sub(s.pendingPressAt) <= binding.doubleTapWindow {
+				s.pendingPress = false
+				s.doubleTapped = true
+				events = append(events, keyEventDoubleTap)
+			} else {
+				s.pendingPress = true
+				s.pendingPressAt = now
+			}
+		} else if !hasHold {
+			events = append(events, keyEventPress)
+		}
+
+	case !down && s.down:
+		s.down = false
+
+		// keys with a hold action only know they were pressed rather than held once they're released
+		if hasHold && !hasDoubleTap && !s.held {
+			events = append(events, keyEventPress)
+		}
+
+		events = append(events, keyEventRelease)
+	}
+
+	if s.down && !s.doubleTapped {
+		if !s.held && now.Sub(s.downSince) >= binding.holdDelay {
+			s.held = true
+			s.lastHoldAt = now
+			s.pendingPress = false
+			events = append(events, keyEventHold)
+		} else if s.held && binding.repeat > 0 && now.Sub(s.lastHoldAt) >= binding.repeat {
+			s.lastHoldAt = now
+			events = append(events, keyEventHold)
+		}
+	}
+
+	return events
+}
@@ -0,0 +1,139 @@
+package deej
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// keyStep is a key level fed into a keyState, at the given offset from the start, along with the events it should cause
+type keyStep struct {
+	down   bool
+	at     time.Duration
+	events []string
+}
+
+func newTestKeyBinding(repeat time.Duration, events ...string) *keyBinding {
+	binding := &keyBinding{
+		actions:         map[string]*keyAction{},
+		holdDelay:       500 * time.Millisecond,
+		repeat:          repeat,
+		doubleTapWindow: 300 * time.Millisecond,
+	}
+
+	for _, event := range events {
+		binding.actions[event] = &keyAction{kind: keyActionType, value: event}
+	}
+
+	return binding
+}
+
+func TestKeyStateUpdate(t *testing.T) {
+	ms := time.Millisecond
+
+	tests := []struct {
+		name    string
+		binding *keyBinding
+		steps   []keyStep
+	}{
+		{
+			name:    "press fires right away without hold or double tap actions",
+			binding: newTestKeyBinding(0, keyEventPress),
+			steps: []keyStep{
+				{true, 0, []string{keyEventPress}},
+
+				// events without an action are still reported, it's up to the caller to skip them
+				{true, 1000 * ms, []string{keyEventHold}},
+				{false, 1100 * ms, []string{keyEventRelease}},
+			},
+		},
+		{
+			name:    "tap on a key with a hold action presses on release",
+			binding: newTestKeyBinding(0, keyEventPress, keyEventHold),
+			steps: []keyStep{
+				{true, 0, nil},
+				{false, 100 * ms, []string{keyEventPress, keyEventRelease}},
+			},
+		},
+		{
+			name:    "holding past hold_delay holds instead of pressing",
+			binding: newTestKeyBinding(0, keyEventPress, keyEventHold),
+			steps: []keyStep{
+				{true, 0, nil},
+				{true, 400 * ms, nil},
+				{true, 500 * ms, []string{keyEventHold}},
+				{true, 2000 * ms, nil},
+				{false, 2100 * ms, []string{keyEventRelease}},
+			},
+		},
+		{
+			name:    "hold repeats while the key stays down",
+			binding: newTestKeyBinding(200*ms, keyEventHold),
+			steps: []keyStep{
+				{true, 0, nil},
+				{true, 500 * ms, []string{keyEventHold}},
+				{true, 650 * ms, nil},
+				{true, 700 * ms, []string{keyEventHold}},
+				{true, 900 * ms, []string{keyEventHold}},
+				{false, 950 * ms, []string{keyEventRelease}},
+			},
+		},
+		{
+			name:    "second tap within the window is a double tap",
+			binding: newTestKeyBinding(0, keyEventPress, keyEventDoubleTap),
+			steps: []keyStep{
+				{true, 0, nil},
+				{false, 50 * ms, []string{keyEventRelease}},
+				{true, 200 * ms, []string{keyEventDoubleTap}},
+				{false, 250 * ms, []string{keyEventRelease}},
+				{false, 1000 * ms, nil},
+			},
+		},
+		{
+			name:    "single tap on a key with a double tap action presses once the window passes",
+			binding: newTestKeyBinding(0, keyEventPress, keyEventDoubleTap),
+			steps: []keyStep{
+				{true, 0, nil},
+				{false, 50 * ms, []string{keyEventRelease}},
+				{false, 250 * ms, nil},
+				{false, 400 * ms, []string{keyEventPress}},
+			},
+		},
+		{
+			name:    "second tap after the window is two presses",
+			binding: newTestKeyBinding(0, keyEventPress, keyEventDoubleTap),
+			steps: []keyStep{
+				{true, 0, nil},
+				{false, 50 * ms, []string{keyEventRelease}},
+				{true, 500 * ms, []string{keyEventPress}},
+				{false, 550 * ms, []string{keyEventRelease}},
+				{false, 900 * ms, []string{keyEventPress}},
+			},
+		},
+		{
+			name:    "keys without a binding report nothing",
+			binding: nil,
+			steps: []keyStep{
+				{true, 0, nil},
+				{true, 1000 * ms, nil},
+				{false, 1100 * ms, nil},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			state := &keyState{}
+			start := time.Now()
+
+			for stepIdx, step := range test.steps {
+				events := state.update(step.down, start.Add(step.at), test.binding)
+
+				if !reflect.DeepEqual(events, step.events) {
+					t.Errorf("step %d (down: %v at %s): expected events %v, got %v",
+						stepIdx, step.down, step.at, step.events, events)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package deej
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// keyLayers turns one key (or the encoder's button) into a layer key, which switches every other key
+// to the bindings of another layer. layers are named sections of key_mapping
+type keyLayers struct {
+	key     int  // -1 when no key is the layer key
+	encoder bool // set when the encoder's button is the layer key instead
+
+	mode  string
+	order []string // the layers to switch between, not including the base layer
+
+	// whether to keep the current layer's name on the deck's display
+	show bool
+}
+
+const (
+	keyLayersKeyKey   = "key"
+	keyLayersKeyMode  = "mode"
+	keyLayersKeyOrder = "order"
+	keyLayersKeyShow  = "show"
+
+	keyLayersEncoder = "encoder"
+
+	// the first layer is active for as long as the layer key is held
+	keyLayersModeHold = "hold"
+
+	// each press of the layer key switches to the next layer, and back to the base layer after the last one
+	keyLayersModeToggle = "toggle"
+
+	// the base layer is made of key_mapping's numbered entries
+	baseKeyLayer = ""
+)
+
+// keyLayersFromConfig parses the key_layers section, given the layers found in key_mapping
+func keyLayersFromConfig(value interface{}, layerMapping map[string]map[int]*keyBinding) (*keyLayers, error) {
+	layers := &keyLayers{
+		key:  -1,
+		mode: keyLayersModeHold,
+	}
+
+	if value == nil {
+		return layers, nil
+	}
+
+	fields, ok := toStringKeyedMap(value)
+	if !ok {
+		return layers, errors.New("key layers must be a set of fields")
+	}
+
+	if len(fields) == 0 {
+		return layers, nil
+	}
+
+	keyValue := strings.ToLower(fmt.Sprint(fields[keyLayersKeyKey]))
+	if keyValue == keyLayersEncoder {
+		layers.encoder = true
+	} else {
+		key, err := strconv.Atoi(keyValue)
+		if err != nil || key < 0 {
+			return layers, fmt.Errorf("layer key must be a key index or %q, got %v", keyLayersEncoder, fields[keyLayersKeyKey])
+		}
+
+		layers.key = key
+	}
+
+	if modeValue, ok := fields[keyLayersKeyMode]; ok {
+		layers.mode = strings.ToLower(fmt.Sprint(modeValue))
+
+		if layers.mode != keyLayersModeHold && layers.mode != keyLayersModeToggle {
+			return disabledKeyLayers(), fmt.Errorf("unknown layer mode %q", layers.mode)
+		}
+	}
+
+	if orderValue, ok := fields[keyLayersKeyOrder]; ok {
+		for _, name := range toStringSlice(orderValue) {
+			name = strings.ToLower(name)
+			if _, ok := layerMapping[name]; !ok {
+				return disabledKeyLayers(), fmt.Errorf("layer %q isn't in the key mapping", name)
+			}
+
+			layers.order = append(layers.order, name)
+		}
+	} else {
+		for name := range layerMapping {
+			layers.order = append(layers.order, name)
+		}
+
+		sort.Strings(layers.order)
+	}
+
+	if len(layers.order) == 0 {
+		return disabledKeyLayers(), errors.New("a layer key needs at least one layer in the key mapping")
+	}
+
+	layers.show, _ = fields[keyLayersKeyShow].(bool)
+
+	return layers, nil
+}
+
+func disabledKeyLayers() *keyLayers {
+	return &keyLayers{key: -1, mode: keyLayersModeHold}
+}
+
+func (l *keyLayers) String() string {
+	if l.key < 0 && !l.encoder {
+		return "<no layers>"
+	}
+
+	key := strconv.Itoa(l.key)
+	if l.encoder {
+		key = keyLayersEncoder
+	}
+
+	return fmt.Sprintf("<layers: %v, key: %s, mode: %s>", l.order, key, l.mode)
+}
+
+// nextLayer returns the layer the layer key switches to from the given one
+func (l *keyLayers) nextLayer(current string) string {
+	if current == baseKeyLayer {
+		return l.order[0]
+	}
+
+	for layerIdx, name := range l.order {
+		if name == current && layerIdx+1 < len(l.order) {
+			return l.order[layerIdx+1]
+		}
+	}
+
+	return baseKeyLayer
+}
+
+// layerKeyChanged switches layers as the layer key goes down or up
+func (kc *KeyboardController) layerKeyChanged(down bool) {
+	layers := kc.deej.config.KeyLayers
+
+	kc.layerLock.Lock()
+
+	previous := kc.currentLayer
+
+	switch {
+	case layers.mode == keyLayersModeHold && down:
+		kc.currentLayer = layers.order[0]
+	case layers.mode == keyLayersModeHold:
+		kc.currentLayer = baseKeyLayer
+	case down:
+		kc.currentLayer = layers.nextLayer(kc.currentLayer)
+	}
+
+	current := kc.currentLayer
+
+	kc.layerLock.Unlock()
+
+	if current == previous {
+		return
+	}
+
+	kc.logger.Infow("Switched key layer", "from", previous, "to", current)
+
+	if layers.show {
+		status := ""
+		if current != baseKeyLayer {
+			status = fmt.Sprintf("Layer: %s", current)
+		}
+
+		kc.deej.serial.SetDisplayStatus(status)
+	}
+}
+
+// bindingFor returns the binding of a key, looking at the current layer, then the focused app's mapping
+// (if any) and then the base layer. the layer key itself has no binding
+func (kc *KeyboardController) bindingFor(key int, appMapping map[int]*keyBinding) *keyBinding {
+	config := kc.deej.config
+
+	if key == config.KeyLayers.key {
+		return nil
+	}
+
+	kc.layerLock.Lock()
+	current := kc.currentLayer
+	kc.layerLock.Unlock()
+
+	if binding, ok := config.KeyLayerMapping[current][key]; ok {
+		return binding
+	}
+
+	if binding, ok := appMapping[key]; ok {
+		return binding
+	}
+
+	return config.KeyMapping[key]
+}
@@ -0,0 +1,120 @@
+package deej
+
+import (
+	"testing"
+)
+
+func TestKeyLayersNextLayer(t *testing.T) {
+	layers := &keyLayers{key: 5, mode: keyLayersModeToggle, order: []string{"media", "obs"}}
+
+	// toggling goes through every layer in order, and then back to the base layer
+	expected := []string{"media", "obs", baseKeyLayer, "media"}
+	current := baseKeyLayer
+
+	for stepIdx, next := range expected {
+		current = layers.nextLayer(current)
+		if current != next {
+			t.Fatalf("press %d: expected layer %q, got %q", stepIdx+1, next, current)
+		}
+	}
+
+	// a layer that's gone (i.e. after a config reload) goes back to the base layer
+	if next := layers.nextLayer("removed"); next != baseKeyLayer {
+		t.Errorf("expected an unknown layer to go back to the base layer, got %q", next)
+	}
+}
+
+func TestKeyLayersFromConfig(t *testing.T) {
+	layerMapping := map[string]map[int]*keyBinding{
+		"obs":   {},
+		"media": {},
+	}
+
+	layers, err := keyLayersFromConfig(map[interface{}]interface{}{"key": 5, "mode": "Toggle"}, layerMapping)
+	if err != nil {
+		t.Fatalf("parse key layers: %v", err)
+	}
+
+	if layers.key != 5 || layers.encoder || layers.mode != keyLayersModeToggle {
+		t.Errorf("expected key 5 in toggle mode, got %s", layers)
+	}
+
+	// without an order, layers go alphabetically
+	if len(layers.order) != 2 || layers.order[0] != "media" || layers.order[1] != "obs" {
+		t.Errorf("expected layers [media obs], got %v", layers.order)
+	}
+
+	layers, err = keyLayersFromConfig(map[interface{}]interface{}{
+		"key":   "encoder",
+		"order": []interface{}{"OBS"},
+	}, layerMapping)
+	if err != nil {
+		t.Fatalf("parse key layers: %v", err)
+	}
+
+	if layers.key != -1 || !layers.encoder || layers.mode != keyLayersModeHold {
+		t.Errorf("expected the encoder in hold mode, got %s", layers)
+	}
+
+	if len(layers.order) != 1 || layers.order[0] != "obs" {
+		t.Errorf("expected layers [obs], got %v", layers.order)
+	}
+
+	invalid := []map[interface{}]interface{}{
+		{"key": "knob"},
+		{"key": 5, "mode": "sometimes"},
+		{"key": 5, "order": []interface{}{"missing"}},
+	}
+
+	for _, value := range invalid {
+		layers, err := keyLayersFromConfig(value, layerMapping)
+		if err == nil {
+			t.Errorf("expected %v to be invalid, got %s", value, layers)
+		}
+
+		if layers.key >= 0 || layers.encoder {
+			t.Errorf("expected %v to leave layers disabled, got %s", value, layers)
+		}
+	}
+
+	if _, err := keyLayersFromConfig(map[interface{}]interface{}{"key": 5}, nil); err == nil {
+		t.Error("expected a layer key without any layers to be invalid")
+	}
+}
+
+func TestKeyLayersBinding(t *testing.T) {
+	kc, _ := newTestKeyboardController(t)
+	config := kc.deej.config
+
+	baseBinding := newTestKeyBinding(0, keyEventPress)
+	obsBinding := newTestKeyBinding(0, keyEventPress)
+
+	config.KeyMapping = map[int]*keyBinding{0: baseBinding, 1: baseBinding, 5: baseBinding}
+	config.KeyLayerMapping = map[string]map[int]*keyBinding{"obs": {0: obsBinding}}
+	config.KeyLayers = &keyLayers{key: 5, mode: keyLayersModeHold, order: []string{"obs"}}
+
+	if binding := kc.bindingFor(0, nil); binding != baseBinding {
+		t.Errorf("expected key 0 to use the base layer before the layer key goes down")
+	}
+
+	kc.layerKeyChanged(true)
+
+	if binding := kc.bindingFor(0, nil); binding != obsBinding {
+		t.Errorf("expected key 0 to use the obs layer while the layer key is held")
+	}
+
+	// keys the layer doesn't bind fall through to the base layer, and the layer key has no binding of its own
+	if binding := kc.bindingFor(1, nil); binding != baseBinding {
+		t.Errorf("expected key 1 to fall through to the base layer")
+	}
+
+	if binding := kc.bindingFor(5, nil); binding != nil {
+		t.Errorf("expected the layer key to have no binding")
+	}
+
+	kc.layerKeyChanged(false)
+
+	if binding := kc.bindingFor(0, nil); binding != baseBinding {
+		t.Errorf("expected key 0 to go back to the base layer once the layer key is released")
+	}
+}
@@ -0,0 +1,172 @@
+package deej
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// KeySender sends key combinations to whichever window has focus. deej picks one of several backends
+// based on the key_sender config option and what's available on the machine
+type KeySender interface {
+
+	// SendKeyCombo presses the combo's modifiers and key, and then lets go of them
+	SendKeyCombo(combo *KeyCombo) error
+
+	// TypeText types out text as if it were typed on the keyboard
+	TypeText(text string) error
+
+	// Close releases anything the sender holds on to (such as a virtual keyboard)
+	Close() error
+}
+
+const (
+	keySenderAuto       = "auto"       // the best backend available, see newKeySender
+	keySenderUinput     = "uinput"     // a virtual keyboard through /dev/uinput (linux only)
+	keySenderXdotool    = "xdotool"    // the xdotool command (linux, x11 only)
+	keySenderYdotool    = "ydotool"    // the ydotool command (linux, needs ydotoold running)
+	keySenderPowershell = "powershell" // SendKeys through powershell (windows only)
+	keySenderRecorder   = "recorder"   // doesn't send anything, only keeps track of what it was asked to send
+)
+
+var keySenderNames = []string{
+	keySenderAuto, keySenderUinput, keySenderXdotool, keySenderYdotool, keySenderPowershell, keySenderRecorder,
+}
+
+// RecordingKeySender is a KeySender that doesn't send anything. it keeps the combos and text it was asked to send instead,
+// which lets key actions be exercised without a desktop
+type RecordingKeySender struct {
+	combos []KeyCombo
+	texts  []string
+	lock   sync.Mutex
+}
+
+// NewRecordingKeySender creates an empty RecordingKeySender
+func NewRecordingKeySender() *RecordingKeySender {
+	return &RecordingKeySender{}
+}
+
+// SendKeyCombo records the combo
+func (r *RecordingKeySender) SendKeyCombo(combo *KeyCombo) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.combos = append(r.combos, KeyCombo{
+		Modifiers: append([]string(nil), combo.Modifiers...),
+		Key:       combo.Key,
+	})
+
+	return nil
+}
+
+// Combos returns every combo recorded so far, oldest first
+func (r *RecordingKeySender) Combos() []KeyCombo {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return append([]KeyCombo{}, r.combos...)
+}
+
+// TypeText records the text
+func (r *RecordingKeySender) TypeText(text string) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.texts = append(r.texts, text)
+
+	return nil
+}
+
+// Texts returns every text recorded so far, oldest first
+func (r *RecordingKeySender) Texts() []string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return append([]string{}, r.texts...)
+}
+
+// Close does nothing, as there's nothing to release
+func (r *RecordingKeySender) Close() error {
+	return nil
+}
+
+// SetKeySender makes deej send keys through the given sender rather than the one named in the config.
+// this is mostly useful with a RecordingKeySender
+func (d *Deej) SetKeySender(sender KeySender) {
+	d.keySenderLock.Lock()
+	defer d.keySenderLock.Unlock()
+
+	d.closeKeySender()
+
+	d.keySender = sender
+	d.keySenderName = ""
+	d.keySenderFixed = true
+}
+
+// sendKeyCombo sends a combo through the configured key sender
+func (d *Deej) sendKeyCombo(combo *KeyCombo) error {
+	d.keySenderLock.Lock()
+	defer d.keySenderLock.Unlock()
+
+	sender, err := d.currentKeySender()
+	if err != nil {
+		return err
+	}
+
+	return sender.SendKeyCombo(combo)
+}
+
+// typeText types text through the configured key sender
+func (d *Deej) typeText(text string) error {
+	d.keySenderLock.Lock()
+	defer d.keySenderLock.Unlock()
+
+	sender, err := d.currentKeySender()
+	if err != nil {
+		return err
+	}
+
+	return sender.TypeText(text)
+}
+
+// returns the configured key sender, which is (re)created whenever the config names a different one.
+// assumes keySenderLock is held
+func (d *Deej) currentKeySender() (KeySender, error) {
+	if !d.keySenderFixed && (d.keySender == nil || d.keySenderName != d.config.KeySender) {
+		d.closeKeySender()
+
+		sender, err := newKeySender(d.config.KeySender, d.logger)
+		if err != nil {
+			return nil, fmt.Errorf("create key sender: %w", err)
+		}
+
+		d.keySender = sender
+		d.keySenderName = d.config.KeySender
+	}
+
+	return d.keySender, nil
+}
+
+// closes the current key sender, if any. assumes keySenderLock is held
+func (d *Deej) closeKeySender() {
+	if d.keySender == nil {
+		return
+	}
+
+	if err := d.keySender.Close(); err != nil {
+		d.logger.Warnw("Failed to close key sender", "error", err)
+	}
+
+	d.keySender = nil
+}
+
+// validKeySender returns true if the name is one of the known key senders, whether or not it's available here
+func validKeySender(name string) bool {
+	for _, known := range keySenderNames {
+		if strings.ToLower(name) == known {
+			return true
+		}
+	}
+
+	return false
+}
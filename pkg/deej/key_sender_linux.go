@@ -0,0 +1,210 @@
+package deej
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"syscall"
+
+	"go.uber.org/zap"
+)
+
+// newKeySender creates the named key sender. "auto" goes for a virtual keyboard if /dev/uinput can be written to,
+// and falls back on ydotool (under wayland) or xdotool (under x11) if they're installed
+func newKeySender(name string, logger *zap.SugaredLogger) (KeySender, error) {
+	if name == keySenderAuto {
+		name = detectKeySender()
+		logger.Infow("Detected key sender", "sender", name)
+	}
+
+	switch name {
+	case keySenderUinput:
+		return &uinputKeySender{}, nil
+	case keySenderXdotool:
+		return &xdotoolKeySender{}, nil
+	case keySenderYdotool:
+		return &ydotoolKeySender{}, nil
+	case keySenderRecorder:
+		return NewRecordingKeySender(), nil
+	}
+
+	return nil, fmt.Errorf("key sender %q isn't available on Linux", name)
+}
+
+func detectKeySender() string {
+	if syscall.Access(uinputPath, 2 /* W_OK */) == nil {
+		return keySenderUinput
+	}
+
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		if _, err := exec.LookPath("ydotool"); err == nil {
+			return keySenderYdotool
+		}
+	}
+
+	if os.Getenv("DISPLAY") != "" {
+		if _, err := exec.LookPath("xdotool"); err == nil {
+			return keySenderXdotool
+		}
+	}
+
+	// nothing else is around, so go with uinput and let its error explain what's missing
+	return keySenderUinput
+}
+
+// uinputKeySender sends keys through a virtual keyboard, created on first use and kept around
+// as each new device takes a moment to settle
+type uinputKeySender struct {
+	kb   *uinputKeyboard
+	lock sync.Mutex
+}
+
+func (s *uinputKeySender) SendKeyCombo(combo *KeyCombo) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	// failures aren't kept, so fixing /dev/uinput's permissions doesn't take a restart
+	if s.kb == nil {
+		kb, err := newUinputKeyboard()
+		if err != nil {
+			return fmt.Errorf("create virtual keyboard: %w", err)
+		}
+
+		s.kb = kb
+	}
+
+	if err := s.kb.sendKeyCombo(combo); err != nil {
+
+		// drop the device so the next key press starts fresh
+		s.kb.close()
+		s.kb = nil
+
+		return err
+	}
+
+	return nil
+}
+
+// TypeText types each character as the key combo that produces it, which assumes a US layout
+func (s *uinputKeySender) TypeText(text string) error {
+	for _, char := range text {
+		combo, err := keyComboForChar(char)
+		if err != nil {
+			return err
+		}
+
+		if err := s.SendKeyCombo(combo); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *uinputKeySender) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.kb == nil {
+		return nil
+	}
+
+	err := s.kb.close()
+	s.kb = nil
+
+	return err
+}
+
+// xdotool's names (X keysyms) for keys that aren't a single letter or digit
+var xdotoolKeyNames = map[string]string{
+	keyModifierCtrl: "ctrl", keyModifierShift: "shift", keyModifierAlt: "alt", keyModifierSuper: "super",
+
+	"esc": "Escape", "enter": "Return", "tab": "Tab", "space": "space", "backspace": "BackSpace",
+	"delete": "Delete", "insert": "Insert", "home": "Home", "end": "End", "pageup": "Prior", "pagedown": "Next",
+	"up": "Up", "down": "Down", "left": "Left", "right": "Right", "capslock": "Caps_Lock", "numlock": "Num_Lock",
+	"scrolllock": "Scroll_Lock", "printscreen": "Print", "pause": "Pause", "menu": "Menu",
+	"minus": "minus", "equal": "equal", "comma": "comma", "period": "period", "slash": "slash",
+	"backslash": "backslash", "semicolon": "semicolon", "apostrophe": "apostrophe", "grave": "grave",
+	"leftbracket": "bracketleft", "rightbracket": "bracketright",
+	"volumeup": "XF86AudioRaiseVolume", "volumedown": "XF86AudioLowerVolume", "mute": "XF86AudioMute",
+	"playpause": "XF86AudioPlay", "nexttrack": "XF86AudioNext", "prevtrack": "XF86AudioPrev",
+	"stop": "XF86AudioStop", "brightnessup": "XF86MonBrightnessUp", "brightnessdown": "XF86MonBrightnessDown",
+}
+
+// xdotoolKeySender runs xdotool for every combo. it only works under x11
+type xdotoolKeySender struct{}
+
+func (s *xdotoolKeySender) SendKeyCombo(combo *KeyCombo) error {
+	names := make([]string, 0, len(combo.Modifiers)+1)
+
+	for _, name := range append(append([]string{}, combo.Modifiers...), combo.Key) {
+		if keysym, ok := xdotoolKeyNames[name]; ok {
+			name = keysym
+		} else if number := functionKeyNumber(name); number > 0 {
+			name = fmt.Sprintf("F%d", number)
+		}
+
+		names = append(names, name)
+	}
+
+	return runKeySenderCommand("xdotool", "key", "--clearmodifiers", strings.Join(names, "+"))
+}
+
+func (s *xdotoolKeySender) TypeText(text string) error {
+	return runKeySenderCommand("xdotool", "type", "--clearmodifiers", "--", text)
+}
+
+func (s *xdotoolKeySender) Close() error {
+	return nil
+}
+
+// ydotoolKeySender runs ydotool (1.0 or later, which talks to ydotoold) for every combo.
+// it takes the same key codes as uinput
+type ydotoolKeySender struct{}
+
+func (s *ydotoolKeySender) SendKeyCombo(combo *KeyCombo) error {
+	names := append(append([]string{}, combo.Modifiers...), combo.Key)
+	args := make([]string, 0, len(names)*2+1)
+	args = append(args, "key")
+
+	// press everything in order, then let go of it in reverse
+	for _, name := range names {
+		code, ok := uinputKeyCodes[name]
+		if !ok {
+			return fmt.Errorf("no key code for %s", name)
+		}
+
+		args = append(args, fmt.Sprintf("%d:1", code))
+	}
+
+	for nameIdx := len(names) - 1; nameIdx >= 0; nameIdx-- {
+		args = append(args, fmt.Sprintf("%d:0", uinputKeyCodes[names[nameIdx]]))
+	}
+
+	return runKeySenderCommand("ydotool", args...)
+}
+
+func (s *ydotoolKeySender) TypeText(text string) error {
+	return runKeySenderCommand("ydotool", "type", "--", text)
+}
+
+func (s *ydotoolKeySender) Close() error {
+	return nil
+}
+
+func runKeySenderCommand(name string, args ...string) error {
+	output, err := exec.Command(name, args...).CombinedOutput()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(output) > 0 {
+			return fmt.Errorf("run %s: %w (%s)", name, err, strings.TrimSpace(string(output)))
+		}
+
+		return fmt.Errorf("run %s: %w", name, err)
+	}
+
+	return nil
+}
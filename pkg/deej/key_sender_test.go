@@ -0,0 +1,135 @@
+package deej
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// newTestKeyboardController returns a keyboard controller whose keys go to a recorder instead of the desktop
+func newTestKeyboardController(t *testing.T) (*KeyboardController, *RecordingKeySender) {
+	t.Helper()
+
+	d := &Deej{
+		logger: zap.NewNop().Sugar(),
+		config: &CanonicalConfig{
+			KeyLayers: disabledKeyLayers(),
+			Actions:   &actionSettings{timeout: time.Second},
+		},
+	}
+
+	recorder := NewRecordingKeySender()
+	d.SetKeySender(recorder)
+
+	return NewKeyboardController(d, d.logger), recorder
+}
+
+func mustParseKeyAction(t *testing.T, value interface{}) *keyAction {
+	t.Helper()
+
+	action, err := parseKeyAction(value)
+	if err != nil {
+		t.Fatalf("parse action %v: %v", value, err)
+	}
+
+	return action
+}
+
+func TestRecordingKeySenderHotkey(t *testing.T) {
+	kc, recorder := newTestKeyboardController(t)
+
+	action := mustParseKeyAction(t, map[interface{}]interface{}{"hotkey": "Ctrl+Shift+Escape"})
+	if err := kc.runKeyAction(context.Background(), action); err != nil {
+		t.Fatalf("run hotkey action: %v", err)
+	}
+
+	expected := []KeyCombo{{Modifiers: []string{keyModifierCtrl, keyModifierShift}, Key: "esc"}}
+	if combos := recorder.Combos(); !reflect.DeepEqual(combos, expected) {
+		t.Errorf("expected combos %v, got %v", expected, combos)
+	}
+
+	if texts := recorder.Texts(); len(texts) != 0 {
+		t.Errorf("expected no text, got %q", texts)
+	}
+}
+
+func TestRecordingKeySenderType(t *testing.T) {
+	kc, recorder := newTestKeyboardController(t)
+
+	// leading and trailing spaces are part of the text
+	action := mustParseKeyAction(t, map[interface{}]interface{}{"type": " brb "})
+	if err := kc.runKeyAction(context.Background(), action); err != nil {
+		t.Fatalf("run type action: %v", err)
+	}
+
+	if texts := recorder.Texts(); !reflect.DeepEqual(texts, []string{" brb "}) {
+		t.Errorf("expected text %q, got %q", " brb ", texts)
+	}
+}
+
+func TestRecordingKeySenderMacro(t *testing.T) {
+	kc, recorder := newTestKeyboardController(t)
+
+	action := mustParseKeyAction(t, map[interface{}]interface{}{
+		"macro": []interface{}{
+			map[interface{}]interface{}{"hotkey": "Ctrl+A"},
+			map[interface{}]interface{}{"type": "/away"},
+			map[interface{}]interface{}{"wait": 1},
+			map[interface{}]interface{}{"repeat": map[interface{}]interface{}{
+				"times": 2,
+				"steps": []interface{}{map[interface{}]interface{}{"hotkey": "Enter"}},
+			}},
+		},
+	})
+
+	if err := kc.runKeyAction(context.Background(), action); err != nil {
+		t.Fatalf("run macro action: %v", err)
+	}
+
+	expectedCombos := []KeyCombo{
+		{Modifiers: []string{keyModifierCtrl}, Key: "a"},
+		{Key: "enter"},
+		{Key: "enter"},
+	}
+
+	if combos := recorder.Combos(); !reflect.DeepEqual(combos, expectedCombos) {
+		t.Errorf("expected combos %v, got %v", expectedCombos, combos)
+	}
+
+	if texts := recorder.Texts(); !reflect.DeepEqual(texts, []string{"/away"}) {
+		t.Errorf("expected text %q, got %q", "/away", texts)
+	}
+}
+
+func TestRecordingKeySenderKeyPress(t *testing.T) {
+	kc, recorder := newTestKeyboardController(t)
+
+	mapping, _, err := keyMappingFromConfig(map[string]interface{}{
+		"1": map[interface{}]interface{}{"hotkey": "PlayPause"},
+	})
+	if err != nil {
+		t.Fatalf("parse key mapping: %v", err)
+	}
+
+	kc.deej.config.KeyMapping = mapping
+
+	for _, line := range []string{"0|0|0", "0|1|0", "0|0|0"} {
+		if err := kc.HandleKeyboardInfo(line); err != nil {
+			t.Fatalf("handle keyboard info %q: %v", line, err)
+		}
+	}
+
+	// actions run in the background, so give the executor a moment
+	deadline := time.Now().Add(time.Second)
+	for len(recorder.Combos()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	expected := []KeyCombo{{Key: "playpause"}}
+	if combos := recorder.Combos(); !reflect.DeepEqual(combos, expected) {
+		t.Errorf("expected combos %v, got %v", expected, combos)
+	}
+}
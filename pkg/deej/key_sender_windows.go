@@ -0,0 +1,113 @@
+package deej
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// newKeySender creates the named key sender. "auto" always means powershell on Windows
+func newKeySender(name string, logger *zap.SugaredLogger) (KeySender, error) {
+	switch name {
+	case keySenderAuto, keySenderPowershell:
+		return &powershellKeySender{}, nil
+	case keySenderRecorder:
+		return NewRecordingKeySender(), nil
+	}
+
+	return nil, fmt.Errorf("key sender %q isn't available on Windows", name)
+}
+
+// modifiers as SendKeys writes them. it has no way of sending the windows key
+var sendKeysModifiers = map[string]string{
+	keyModifierCtrl:  "^",
+	keyModifierShift: "+",
+	keyModifierAlt:   "%",
+}
+
+// keys as SendKeys writes them, for the ones that aren't a single letter or digit
+var sendKeysNames = map[string]string{
+	"esc": "{ESC}", "enter": "{ENTER}", "tab": "{TAB}", "space": " ", "backspace": "{BACKSPACE}",
+	"delete": "{DELETE}", "insert": "{INSERT}", "home": "{HOME}", "end": "{END}", "pageup": "{PGUP}",
+	"pagedown": "{PGDN}", "up": "{UP}", "down": "{DOWN}", "left": "{LEFT}", "right": "{RIGHT}",
+	"capslock": "{CAPSLOCK}", "numlock": "{NUMLOCK}", "scrolllock": "{SCROLLLOCK}", "printscreen": "{PRTSC}",
+	"pause": "{BREAK}", "minus": "-", "equal": "=", "comma": ",", "period": ".", "slash": "/", "backslash": "\\",
+	"semicolon": ";", "apostrophe": "'", "grave": "`", "leftbracket": "{[}", "rightbracket": "{]}",
+}
+
+// powershellKeySender sends keys through SendKeys, starting powershell for every combo
+type powershellKeySender struct{}
+
+func (s *powershellKeySender) SendKeyCombo(combo *KeyCombo) error {
+	sequence, err := sendKeysSequence(combo)
+	if err != nil {
+		return err
+	}
+
+	return runSendKeys(sequence)
+}
+
+func runSendKeys(sequence string) error {
+	cmd := exec.Command("powershell", "-Command",
+		fmt.Sprintf("$wshell = New-Object -ComObject wscript.shell; $wshell.SendKeys(%s)", powershellQuote(sequence)))
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("run powershell: %w", err)
+	}
+
+	return nil
+}
+
+// TypeText sends the text through SendKeys, with the characters it treats specially wrapped in braces
+func (s *powershellKeySender) TypeText(text string) error {
+	var sequence strings.Builder
+
+	for _, char := range text {
+		switch char {
+		case '+', '^', '%', '~', '(', ')', '{', '}', '[', ']':
+			sequence.WriteString("{" + string(char) + "}")
+		case '\n':
+			sequence.WriteString("{ENTER}")
+		default:
+			sequence.WriteRune(char)
+		}
+	}
+
+	return runSendKeys(sequence.String())
+}
+
+func (s *powershellKeySender) Close() error {
+	return nil
+}
+
+// sendKeysSequence writes a key combination in SendKeys syntax, i.e. "^+{ESC}" for Ctrl+Shift+Esc
+func sendKeysSequence(combo *KeyCombo) (string, error) {
+	var sequence string
+
+	for _, modifier := range combo.Modifiers {
+		sendKeysModifier, ok := sendKeysModifiers[modifier]
+		if !ok {
+			return "", fmt.Errorf("the %s modifier isn't supported on Windows", modifier)
+		}
+
+		sequence += sendKeysModifier
+	}
+
+	if len(combo.Key) == 1 {
+		return sequence + combo.Key, nil
+	}
+
+	// SendKeys only goes up to F16
+	if number := functionKeyNumber(combo.Key); number > 0 && number <= 16 {
+		return fmt.Sprintf("%s{F%d}", sequence, number), nil
+	}
+
+	if name, ok := sendKeysNames[combo.Key]; ok {
+		return sequence + name, nil
+	}
+
+	return "", errors.New("key isn't supported on Windows")
+}
@@ -1,24 +1,56 @@
 package deej
 
 import (
-	"errors"
+	"context"
 	"fmt"
-	"os/exec"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"go.uber.org/zap"
 )
 
 // KeyboardController handles keyboard press events
 type KeyboardController struct {
+	deej   *Deej
 	logger *zap.SugaredLogger
+
+	// each key's state as of the last line read from serial, so events can be told apart
+	keyStates     []*keyState
+	keyStatesLock sync.Mutex
+
+	// how many keys the last line had, or 0 to look the keys over again on the next one. like keyStates,
+	// it's guarded by keyStatesLock, since config reloads reset it while serial lines are being handled
+	lastKnownNumKeys int
+
+	// the key layer currently in effect, see keyLayers
+	currentLayer string
+	layerLock    sync.Mutex
+
+	// cancels each running macro, by what started it (a key or a chord) and on which event (i.e. "key 3 hold")
+	runningMacros map[string]context.CancelFunc
+	macrosLock    sync.Mutex
+
+	// actions currently running, by what makes them identical (see executeAction)
+	runningActions     map[string]bool
+	runningActionsLock sync.Mutex
+}
+
+// keyEventAction is an action due to run for one of a key's (or a chord's) events
+type keyEventAction struct {
+	source string // i.e. "key 3" or "chord 0+5"
+	event  string
+	action *keyAction
 }
 
 // NewKeyboardController initializes a new KeyboardController instance
-func NewKeyboardController(logger *zap.SugaredLogger) *KeyboardController {
+func NewKeyboardController(deej *Deej, logger *zap.SugaredLogger) *KeyboardController {
 	return &KeyboardController{
-		logger: logger.Named("keyboard"),
+		deej:           deej,
+		logger:         logger.Named("keyboard"),
+		runningMacros:  map[string]context.CancelFunc{},
+		runningActions: map[string]bool{},
 	}
 }
 
@@ -26,93 +58,183 @@ func NewKeyboardController(logger *zap.SugaredLogger) *KeyboardController {
 func (kc *KeyboardController) HandleKeyboardInfo(data string) error {
 	kc.logger.Debugw("Received keyboard info", "data", data)
 
-	// Split the data by |
+	// Split the data by |, the deck sends one value per key (however many it has)
 	keyValues := strings.Split(data, "|")
 
-	if len(keyValues) != 6 {
-		return errors.New("keyboard: invalid data format")
-	}
-
-	// Map of key index to the corresponding key combination for Windows
-	keyMap := map[int]string{
-		0: "Ctrl+Shift+Esc",
-		1: "discord",
-		2: "Chatgpt",
-		3: "F8",
-		4: "Alt+Left",
-		5: "F10",
-	}
-
-	// Detect the OS for platform-specific handling
-	osType := detectOSType()
+	keysDown := make([]bool, len(keyValues))
 
-	// Iterate over key values and trigger key presses based on the mapping
 	for idx, valueStr := range keyValues {
 		value, err := strconv.Atoi(valueStr)
 		if err != nil {
 			return fmt.Errorf("keyboard: failed to parse key value %s", valueStr)
 		}
 
-		if value == 1 {
-			keyCombination, ok := keyMap[idx]
-			if !ok {
-				return fmt.Errorf("keyboard: unknown key index %d", idx)
-			}
+		keysDown[idx] = value == 1
+	}
+
+	// work out which events happened under the lock, but run their actions outside of it
+	// so ducking can keep checking which keys are held in the meantime
+	now := time.Now()
+	appMapping := kc.foregroundAppMapping()
+	var due []keyEventAction
+
+	kc.keyStatesLock.Lock()
+
+	numKeys := len(keysDown)
+	keysChanged := numKeys != kc.lastKnownNumKeys
+	kc.lastKnownNumKeys = numKeys
+
+	for len(kc.keyStates) < numKeys {
+		kc.keyStates = append(kc.keyStates, &keyState{})
+	}
 
-			kc.logger.Infow("Sending key press", "key", keyCombination)
+	// keys the deck no longer has shouldn't look held forever
+	kc.keyStates = kc.keyStates[:numKeys]
 
-			// Send key press based on the OS type
-			err := kc.sendKeyPress(osType, keyCombination)
-			if err != nil {
-				kc.logger.Errorw("Failed to send key press", "key", keyCombination, "error", err)
-				return err
+	layerKey := kc.deej.config.KeyLayers.key
+	layerKeyChange := 0 // -1 when the layer key went up, 1 when it went down
+
+	if layerKey >= 0 && layerKey < len(keysDown) && keysDown[layerKey] != kc.keyStates[layerKey].rawDown {
+		layerKeyChange = -1
+		if keysDown[layerKey] {
+			layerKeyChange = 1
+		}
+	}
+
+	updates, chords := kc.resolveChords(keysDown, now)
+
+	for _, chord := range chords {
+		due = append(due, keyEventAction{source: "chord " + chord.String(), event: keyEventPress, action: chord.action})
+	}
+
+	for idx, keyUpdates := range updates {
+		binding := kc.bindingFor(idx, appMapping)
+
+		for _, update := range keyUpdates {
+			for _, event := range kc.keyStates[idx].update(update.down, update.at, binding) {
+				if action, ok := binding.actions[event]; ok {
+					due = append(due, keyEventAction{source: fmt.Sprintf("key %d", idx), event: event, action: action})
+				}
 			}
 		}
 	}
 
+	kc.keyStatesLock.Unlock()
+
+	if keysChanged {
+		kc.logger.Infow("Detected keys", "amount", numKeys)
+		kc.warnUnmappedKeys(numKeys)
+	}
+
+	if layerKeyChange != 0 {
+		kc.layerKeyChanged(layerKeyChange > 0)
+	}
+
+	for _, ea := range due {
+		kc.logger.Debugw("Key event", "source", ea.source, "event", ea.event)
+
+		if ea.action.macro != nil {
+			kc.startMacro(ea)
+			continue
+		}
+
+		action := ea.action
+		kc.executeAction(ea, action.String(), func() error {
+			return kc.runKeyAction(context.Background(), action)
+		})
+	}
+
 	return nil
 }
 
-// detectOSType detects the current operating system type
-func detectOSType() string {
-	// For simplicity, assuming it's always Windows for this example
-	return "windows"
+// recheckKeys has the next line read from serial look the keys over again, i.e. after the config's mappings changed.
+// it's called from the config reload goroutine, so it takes keyStatesLock like HandleKeyboardInfo does
+func (kc *KeyboardController) recheckKeys() {
+	kc.keyStatesLock.Lock()
+	defer kc.keyStatesLock.Unlock()
+
+	kc.lastKnownNumKeys = 0
 }
 
-// sendKeyPress sends a key press based on the OS type and key combination
-func (kc *KeyboardController) sendKeyPress(osType, keyCombination string) error {
-	var cmd *exec.Cmd
-
-	switch osType {
-	case "windows":
-		// Windows-specific command to send key press
-		switch keyCombination {
-		case "Alt+Left":
-			cmd = exec.Command("powershell", "-Command", "$wshell = New-Object -ComObject wscript.shell; $wshell.SendKeys('%{LEFT}')")
-		case "Ctrl+Shift+Esc":
-			cmd = exec.Command("powershell", "-Command", "$wshell = New-Object -ComObject wscript.shell; $wshell.SendKeys('^+{ESC}')")
-		case "discord": 
-            cmd = exec.Command("powershell", "-Command", `Start-Process "C:\Users\Gaming\AppData\Roaming\Microsoft\Windows\Start Menu\Programs\Discord Inc\Discord.lnk"`)
-		case "Chatgpt":
-			cmd = exec.Command("powershell", "-Command", `Start-Process "https://www.chatgpt.com"`)
-		case "F8":
-			cmd = exec.Command("powershell", "-Command", "$wshell = New-Object -ComObject wscript.shell; $wshell.SendKeys('{F8}')")
-		case "F10":
-			cmd = exec.Command("powershell", "-Command", "$wshell = New-Object -ComObject wscript.shell; $wshell.SendKeys('{F10}')")
-		default:
-			return fmt.Errorf("keyboard: unsupported key combination for Windows: %s", keyCombination)
+// warnUnmappedKeys warns about any of the deck's keys that nothing in the config uses, as pressing them does nothing
+func (kc *KeyboardController) warnUnmappedKeys(numKeys int) {
+	var unmapped []int
+
+	for key := 0; key < numKeys; key++ {
+		if !kc.keyMapped(key) {
+			unmapped = append(unmapped, key)
 		}
-	default:
-		return fmt.Errorf("keyboard: unsupported OS: %s", osType)
 	}
 
-	// Execute the command
-	if cmd != nil {
-		err := cmd.Run()
-		if err != nil {
-			return fmt.Errorf("keyboard: failed to execute command for key press: %w", err)
+	if len(unmapped) > 0 {
+		kc.logger.Warnw("Some keys have no mapping and won't do anything", "keys", unmapped)
+	}
+}
+
+// keyMapped returns true if the key has a binding in any layer or app mapping, is part of a chord,
+// is the layer key, or triggers a ducking rule
+func (kc *KeyboardController) keyMapped(key int) bool {
+	config := kc.deej.config
+
+	if _, ok := config.KeyMapping[key]; ok || key == config.KeyLayers.key {
+		return true
+	}
+
+	for _, mapping := range config.KeyLayerMapping {
+		if _, ok := mapping[key]; ok {
+			return true
+		}
+	}
+
+	for _, mapping := range config.AppKeyMapping {
+		if _, ok := mapping[key]; ok {
+			return true
+		}
+	}
+
+	for _, chord := range config.KeyChords {
+		for _, chordKey := range chord.keys {
+			if chordKey == key {
+				return true
+			}
 		}
 	}
 
+	for _, rule := range config.DuckingRules {
+		if rule.key == key {
+			return true
+		}
+	}
+
+	return false
+}
+
+// keyHeld returns true if the given key was down as of the last line read from serial
+func (kc *KeyboardController) keyHeld(idx int) bool {
+	kc.keyStatesLock.Lock()
+	defer kc.keyStatesLock.Unlock()
+
+	return idx < len(kc.keyStates) && kc.keyStates[idx].rawDown
+}
+
+// typeText types out text in whichever window has focus
+func (kc *KeyboardController) typeText(text string) error {
+	kc.logger.Infow("Typing text", "length", len(text))
+
+	if err := kc.deej.typeText(text); err != nil {
+		return fmt.Errorf("keyboard: type text: %w", err)
+	}
+
+	return nil
+}
+
+// sendKeyPress sends a key combination to whichever window has focus
+func (kc *KeyboardController) sendKeyPress(combo *KeyCombo) error {
+	kc.logger.Infow("Sending key press", "key", combo)
+
+	if err := kc.deej.sendKeyCombo(combo); err != nil {
+		return fmt.Errorf("keyboard: send %s: %w", combo, err)
+	}
+
 	return nil
 }
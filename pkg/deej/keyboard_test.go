@@ -0,0 +1,54 @@
+package deej
+
+import (
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestKeyboardRecheckKeys(t *testing.T) {
+	kc, _ := newTestKeyboardController(t)
+
+	core, logs := observer.New(zapcore.InfoLevel)
+	kc.logger = zap.New(core).Sugar()
+
+	handle := func(line string) {
+		if err := kc.HandleKeyboardInfo(line); err != nil {
+			t.Fatalf("handle keyboard info %q: %v", line, err)
+		}
+	}
+
+	detections := func() int {
+		return logs.FilterMessage("Detected keys").Len()
+	}
+
+	handle("0|0|0")
+	handle("0|0|0")
+
+	if count := detections(); count != 1 {
+		t.Fatalf("expected the keys to be detected once, got %d", count)
+	}
+
+	// a config reload has the next line look the keys over again (and warn about unmapped ones), once
+	kc.recheckKeys()
+
+	handle("0|0|0")
+	handle("0|0|0")
+
+	if count := detections(); count != 2 {
+		t.Errorf("expected the keys to be detected again after a recheck, got %d detections", count)
+	}
+
+	if count := logs.FilterMessage("Some keys have no mapping and won't do anything").Len(); count != 2 {
+		t.Errorf("expected unmapped keys to be warned about on both detections, got %d warnings", count)
+	}
+
+	// the deck changing how many keys it has counts as well
+	handle("0|0")
+
+	if count := detections(); count != 3 {
+		t.Errorf("expected a different amount of keys to be detected, got %d detections", count)
+	}
+}
@@ -0,0 +1,234 @@
+package deej
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// macro is a sequence of steps run one after the other, i.e. "mute, type /away, press enter".
+// a running macro is cancelled by pressing its key again
+type macro struct {
+	steps []*macroStep
+}
+
+// macroStep is one of: any key action (including a nested macro), a wait, or a repeated sequence of steps
+type macroStep struct {
+	action *keyAction
+
+	wait time.Duration
+
+	repeat int
+	steps  []*macroStep
+}
+
+const (
+	macroStepWait   = "wait"
+	macroStepRepeat = "repeat"
+
+	macroRepeatKeyTimes = "times"
+	macroRepeatKeySteps = "steps"
+
+	// keeps a typo from tying a key up for good
+	maxMacroRepeat = 1000
+)
+
+var (
+	// errMacroCancelled is returned by a macro that stopped because its key was pressed again
+	errMacroCancelled = errors.New("macro cancelled")
+
+	// errMacroTimedOut is returned by a macro that ran past the action timeout
+	errMacroTimedOut = errors.New("macro timed out")
+)
+
+func parseMacro(value interface{}) (*macro, error) {
+	steps, err := parseMacroSteps(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return &macro{steps: steps}, nil
+}
+
+func parseMacroSteps(value interface{}) ([]*macroStep, error) {
+	items, ok := value.([]interface{})
+	if !ok || len(items) == 0 {
+		return nil, errors.New("a macro needs a list of steps")
+	}
+
+	steps := make([]*macroStep, 0, len(items))
+
+	for itemIdx, item := range items {
+		step, err := parseMacroStep(item)
+		if err != nil {
+			return nil, fmt.Errorf("step %d: %w", itemIdx+1, err)
+		}
+
+		steps = append(steps, step)
+	}
+
+	return steps, nil
+}
+
+func parseMacroStep(value interface{}) (*macroStep, error) {
+	fields, ok := toStringKeyedMap(value)
+
+	if ok && len(fields) == 1 {
+		if _, isWait := fields[macroStepWait]; isWait {
+			wait, err := parseDurationField(fields, macroStepWait, 0)
+			if err != nil {
+				return nil, err
+			}
+
+			if wait < 0 {
+				return nil, errors.New("wait can't be negative")
+			}
+
+			return &macroStep{wait: wait}, nil
+		}
+
+		if repeatValue, isRepeat := fields[macroStepRepeat]; isRepeat {
+			return parseMacroRepeat(repeatValue)
+		}
+	}
+
+	// anything else is a regular action
+	action, err := parseKeyAction(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return &macroStep{action: action}, nil
+}
+
+func parseMacroRepeat(value interface{}) (*macroStep, error) {
+	fields, ok := toStringKeyedMap(value)
+	if !ok {
+		return nil, errors.New("repeat needs times and steps")
+	}
+
+	times, err := strconv.Atoi(fmt.Sprint(fields[macroRepeatKeyTimes]))
+	if err != nil || times < 1 || times > maxMacroRepeat {
+		return nil, fmt.Errorf("repeat times must be between 1 and %d, got %v", maxMacroRepeat, fields[macroRepeatKeyTimes])
+	}
+
+	steps, err := parseMacroSteps(fields[macroRepeatKeySteps])
+	if err != nil {
+		return nil, fmt.Errorf("repeat: %w", err)
+	}
+
+	return &macroStep{repeat: times, steps: steps}, nil
+}
+
+func (m *macro) String() string {
+	return fmt.Sprintf("macro: %d steps", len(m.steps))
+}
+
+// runMacro runs a macro's steps in order, until they're done or ctx is cancelled
+func (kc *KeyboardController) runMacro(ctx context.Context, m *macro) error {
+	return kc.runMacroSteps(ctx, m.steps)
+}
+
+func (kc *KeyboardController) runMacroSteps(ctx context.Context, steps []*macroStep) error {
+	for _, step := range steps {
+		select {
+		case <-ctx.Done():
+			return macroStopped(ctx)
+		default:
+		}
+
+		switch {
+		case step.action != nil:
+			if err := kc.runKeyAction(ctx, step.action); err != nil {
+				return fmt.Errorf("run %s: %w", step.action, err)
+			}
+
+		case step.repeat > 0:
+			for iteration := 0; iteration < step.repeat; iteration++ {
+				if err := kc.runMacroSteps(ctx, step.steps); err != nil {
+					return err
+				}
+			}
+
+		default:
+			select {
+			case <-ctx.Done():
+				return macroStopped(ctx)
+			case <-time.After(step.wait):
+			}
+		}
+	}
+
+	return nil
+}
+
+// macroStopped returns why a macro's ctx is done: its key was pressed again, or it ran out of time
+func macroStopped(ctx context.Context) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return errMacroTimedOut
+	}
+
+	return errMacroCancelled
+}
+
+// startMacro runs a key's (or a chord's) macro through the action executor, or cancels it if it's still running
+// from an earlier press. each of a key's events runs its own macro, so i.e. its press and hold macros are kept apart
+func (kc *KeyboardController) startMacro(ea keyEventAction) {
+	kc.macrosLock.Lock()
+	defer kc.macrosLock.Unlock()
+
+	runningKey := ea.source + " " + ea.event
+
+	if cancel, running := kc.runningMacros[runningKey]; running {
+
+		// a held key repeats its hold event, which leaves the macro from an earlier repeat to finish
+		// rather than cancelling it on every other repeat
+		if ea.event == keyEventHold {
+			kc.logger.Debugw("Skipping hold repeat, its macro is still running", "source", ea.source)
+			return
+		}
+
+		kc.logger.Infow("Cancelling macro", "source", ea.source, "event", ea.event)
+		cancel()
+		delete(kc.runningMacros, runningKey)
+
+		return
+	}
+
+	// the whole macro gets the action timeout, so waits and repeats can't hold on to its executor slot for good
+	var ctx context.Context
+	var cancel context.CancelFunc
+
+	if timeout := kc.deej.config.Actions.timeout; timeout > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), timeout)
+	} else {
+		ctx, cancel = context.WithCancel(context.Background())
+	}
+
+	run := func() error {
+		defer func() {
+			kc.macrosLock.Lock()
+
+			// the key may have started a new run since this one was cancelled, which isn't ours to clear
+			if !errors.Is(ctx.Err(), context.Canceled) {
+				delete(kc.runningMacros, runningKey)
+			}
+
+			kc.macrosLock.Unlock()
+
+			cancel()
+		}()
+
+		return kc.runMacro(ctx, ea.action.macro)
+	}
+
+	// a macro is only ever identical to its own earlier run, which a second press cancels instead
+	if !kc.executeAction(ea, "macro from "+runningKey, run) {
+		cancel()
+		return
+	}
+
+	kc.runningMacros[runningKey] = cancel
+}
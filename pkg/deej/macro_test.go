@@ -0,0 +1,202 @@
+package deej
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseMacro(t *testing.T) {
+	m, err := parseMacro([]interface{}{
+		"save_scene meeting",
+		map[interface{}]interface{}{"type": "/away"},
+		map[interface{}]interface{}{"wait": 150},
+		map[interface{}]interface{}{"wait": "1.5s"},
+		map[interface{}]interface{}{"repeat": map[interface{}]interface{}{
+			"times": 3,
+			"steps": []interface{}{
+				map[interface{}]interface{}{"hotkey": "Down"},
+				map[interface{}]interface{}{"wait": 10},
+			},
+		}},
+		map[interface{}]interface{}{"macro": []interface{}{
+			map[interface{}]interface{}{"hotkey": "Enter"},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("parse macro: %v", err)
+	}
+
+	if len(m.steps) != 6 {
+		t.Fatalf("expected 6 steps, got %d", len(m.steps))
+	}
+
+	if action := m.steps[0].action; action == nil || action.deej == nil || action.deej.name != deejActionSaveScene {
+		t.Errorf("expected step 1 to be a deej action, got %+v", m.steps[0])
+	}
+
+	if action := m.steps[1].action; action == nil || action.kind != keyActionType || action.value != "/away" {
+		t.Errorf("expected step 2 to type /away, got %+v", m.steps[1])
+	}
+
+	// plain numbers are milliseconds
+	if wait := m.steps[2].wait; wait != 150*time.Millisecond {
+		t.Errorf("expected step 3 to wait 150ms, got %s", wait)
+	}
+
+	if wait := m.steps[3].wait; wait != 1500*time.Millisecond {
+		t.Errorf("expected step 4 to wait 1.5s, got %s", wait)
+	}
+
+	if repeat := m.steps[4]; repeat.repeat != 3 || len(repeat.steps) != 2 {
+		t.Errorf("expected step 5 to repeat 2 steps 3 times, got %d times %d steps", repeat.repeat, len(repeat.steps))
+	}
+
+	if action := m.steps[5].action; action == nil || action.macro == nil || len(action.macro.steps) != 1 {
+		t.Errorf("expected step 6 to be a nested macro, got %+v", m.steps[5])
+	}
+}
+
+func TestParseMacroErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		err   string
+	}{
+		{"not a list", "save_scene meeting", "list of steps"},
+		{"no steps", []interface{}{}, "list of steps"},
+		{"negative wait", []interface{}{map[interface{}]interface{}{"wait": -5}}, "negative"},
+		{"invalid wait", []interface{}{map[interface{}]interface{}{"wait": "soon"}}, "invalid wait"},
+		{
+			"too many repeats",
+			[]interface{}{map[interface{}]interface{}{"repeat": map[interface{}]interface{}{
+				"times": maxMacroRepeat + 1,
+				"steps": []interface{}{map[interface{}]interface{}{"hotkey": "A"}},
+			}}},
+			"repeat times",
+		},
+		{
+			"repeat without steps",
+			[]interface{}{map[interface{}]interface{}{"repeat": map[interface{}]interface{}{"times": 2}}},
+			"repeat: a macro needs a list of steps",
+		},
+		{
+			"invalid action names its step",
+			[]interface{}{
+				map[interface{}]interface{}{"hotkey": "A"},
+				map[interface{}]interface{}{"hotkey": "Ctrl+Nope"},
+			},
+			"step 2",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := parseMacro(test.value)
+			if err == nil {
+				t.Fatalf("expected an error containing %q, got none", test.err)
+			}
+
+			if !strings.Contains(err.Error(), test.err) {
+				t.Errorf("expected an error containing %q, got %q", test.err, err)
+			}
+		})
+	}
+}
+
+func TestRunMacroStops(t *testing.T) {
+	kc, recorder := newTestKeyboardController(t)
+
+	m, err := parseMacro([]interface{}{
+		map[interface{}]interface{}{"hotkey": "A"},
+		map[interface{}]interface{}{"wait": "1m"},
+		map[interface{}]interface{}{"hotkey": "B"},
+	})
+	if err != nil {
+		t.Fatalf("parse macro: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if err := kc.runMacro(ctx, m); !errors.Is(err, errMacroTimedOut) {
+		t.Errorf("expected the macro to time out, got %v", err)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+
+	if err := kc.runMacro(ctx, m); !errors.Is(err, errMacroCancelled) {
+		t.Errorf("expected the macro to be cancelled, got %v", err)
+	}
+
+	// only the first run got as far as its first step
+	if combos := recorder.Combos(); len(combos) != 1 || combos[0].Key != "a" {
+		t.Errorf("expected a single A, got %v", combos)
+	}
+}
+
+func TestStartMacroPerEvent(t *testing.T) {
+	kc, recorder := newTestKeyboardController(t)
+
+	action := mustParseKeyAction(t, map[interface{}]interface{}{
+		"macro": []interface{}{
+			map[interface{}]interface{}{"hotkey": "A"},
+			map[interface{}]interface{}{"wait": "1m"},
+		},
+	})
+
+	press := keyEventAction{source: "key 3", event: keyEventPress, action: action}
+	hold := keyEventAction{source: "key 3", event: keyEventHold, action: action}
+
+	running := func() []string {
+		kc.macrosLock.Lock()
+		defer kc.macrosLock.Unlock()
+
+		var keys []string
+		for key := range kc.runningMacros {
+			keys = append(keys, key)
+		}
+
+		sort.Strings(keys)
+		return keys
+	}
+
+	// the key's press and hold macros both run, neither one cancels the other
+	kc.startMacro(press)
+	kc.startMacro(hold)
+
+	if keys := running(); strings.Join(keys, ",") != "key 3 hold,key 3 press" {
+		t.Fatalf("expected the press and hold macros to be running, got %v", keys)
+	}
+
+	for deadline := time.Now().Add(time.Second); len(recorder.Combos()) < 2; time.Sleep(5 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected each macro to send its A, got %v", recorder.Combos())
+		}
+	}
+
+	// the hold repeats while its macro waits, which leaves it running rather than cancelling it
+	kc.startMacro(hold)
+	kc.startMacro(hold)
+
+	if keys := running(); strings.Join(keys, ",") != "key 3 hold,key 3 press" {
+		t.Fatalf("expected hold repeats to leave both macros running, got %v", keys)
+	}
+
+	// pressing the key again only cancels its press macro
+	kc.startMacro(press)
+
+	if keys := running(); strings.Join(keys, ",") != "key 3 hold" {
+		t.Errorf("expected only the hold macro to be left running, got %v", keys)
+	}
+
+	// and none of the repeats started the hold macro over
+	if combos := recorder.Combos(); len(combos) != 2 {
+		t.Errorf("expected two As, got %v", combos)
+	}
+
+}
@@ -0,0 +1,186 @@
+package deej
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/omriharel/deej/pkg/deej/util"
+)
+
+// rememberedVolumes keeps the last known volume of each session key that matches the user's remember_volume
+// list, so apps can start out at that volume the next time they show up
+type rememberedVolumes struct {
+	levels map[string]float32
+	dirty  bool // set when levels has changes that weren't written to disk yet
+}
+
+const (
+	rememberedVolumeFieldSession = "session"
+	rememberedVolumeFieldVolume  = "volume"
+)
+
+// returns true if the session's volume should be remembered
+func (m *sessionMap) shouldRememberVolume(session Session) bool {
+	return m.anyTargetMatchesSession(m.deej.config.RememberVolume, session)
+}
+
+// rememberVolume records a volume deej set for a session, so it can be reapplied when the session next appears
+func (m *sessionMap) rememberVolume(session Session, volume float32) {
+	if !m.shouldRememberVolume(session) {
+		return
+	}
+
+	m.rememberedVolumesLock.Lock()
+	defer m.rememberedVolumesLock.Unlock()
+
+	m.setRememberedVolume(session.Key(), volume)
+}
+
+// assumes rememberedVolumesLock is held
+func (m *sessionMap) setRememberedVolume(key string, volume float32) {
+	volume = util.NormalizeScalar(volume)
+
+	if current, ok := m.rememberedVolumes.levels[key]; ok && current == volume {
+		return
+	}
+
+	m.rememberedVolumes.levels[key] = volume
+	m.rememberedVolumes.dirty = true
+}
+
+// snapshotRememberedVolumes picks up volume changes made outside of deej (i.e. in the OS mixer) before sessions
+// get released. ducked sessions are skipped, as their current volume isn't what the user wants them at
+func (m *sessionMap) snapshotRememberedVolumes() {
+	if len(m.deej.config.RememberVolume) == 0 {
+		return
+	}
+
+	sessions := m.getMatching(m.shouldRememberVolume)
+
+	m.duckingLock.Lock()
+	ducked := map[string]bool{}
+	for key := range m.duckedKeys {
+		ducked[key] = true
+	}
+	m.duckingLock.Unlock()
+
+	m.rememberedVolumesLock.Lock()
+	defer m.rememberedVolumesLock.Unlock()
+
+	for _, session := range sessions {
+		if !ducked[session.Key()] {
+			m.setRememberedVolume(session.Key(), session.GetVolume())
+		}
+	}
+}
+
+// applyRememberedVolumes sets sessions with a key deej didn't see in the previous refresh to their remembered volume
+func (m *sessionMap) applyRememberedVolumes(sessions []Session) {
+	m.rememberedVolumesLock.Lock()
+	defer m.rememberedVolumesLock.Unlock()
+
+	seenKeys := map[string]bool{}
+
+	for _, session := range sessions {
+		key := session.Key()
+		seenKeys[key] = true
+
+		if m.knownKeys[key] {
+			continue
+		}
+
+		volume, ok := m.rememberedVolumes.levels[key]
+		if !ok || !m.shouldRememberVolume(session) {
+			continue
+		}
+
+		m.logger.Debugw("Applying remembered volume to new session", "session", session, "volume", volume)
+
+		if err := session.SetVolume(volume); err != nil {
+			m.logger.Warnw("Failed to apply remembered volume", "session", session, "error", err)
+		}
+	}
+
+	m.knownKeys = seenKeys
+}
+
+// saveRememberedVolumes writes the remembered volumes to the internal config, if they changed since the last write
+func (m *sessionMap) saveRememberedVolumes() {
+	m.rememberedVolumesLock.Lock()
+	defer m.rememberedVolumesLock.Unlock()
+
+	if !m.rememberedVolumes.dirty {
+		return
+	}
+
+	if err := m.deej.config.saveRememberedVolumes(m.rememberedVolumes.levels); err != nil {
+		m.logger.Warnw("Failed to save remembered volumes", "error", err)
+		return
+	}
+
+	m.rememberedVolumes.dirty = false
+}
+
+// saveRememberedVolumes stores remembered volumes in the internal config and writes it to disk.
+// like scenes, they're written as a list since viper would split session keys containing dots
+func (cc *CanonicalConfig) saveRememberedVolumes(levels map[string]float32) error {
+	keys := make([]string, 0, len(levels))
+	for key := range levels {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	entries := make([]interface{}, len(keys))
+	for keyIdx, key := range keys {
+		entries[keyIdx] = map[string]interface{}{
+			rememberedVolumeFieldSession: key,
+			rememberedVolumeFieldVolume:  levels[key],
+		}
+	}
+
+	cc.internalLock.Lock()
+	defer cc.internalLock.Unlock()
+
+	cc.internalConfig.Set(configKeyRememberedVolumes, entries)
+
+	if err := cc.writeInternalConfig(); err != nil {
+		return fmt.Errorf("write internal config: %w", err)
+	}
+
+	return nil
+}
+
+// rememberedVolumesFromConfig parses the volumes saved in the internal config, skipping malformed entries
+func rememberedVolumesFromConfig(value interface{}) map[string]float32 {
+	levels := map[string]float32{}
+
+	entries, ok := value.([]interface{})
+	if !ok {
+		return levels
+	}
+
+	for _, entry := range entries {
+		fields, ok := toStringKeyedMap(entry)
+		if !ok {
+			continue
+		}
+
+		key, _ := fields[rememberedVolumeFieldSession].(string)
+		if key == "" {
+			continue
+		}
+
+		switch volume := fields[rememberedVolumeFieldVolume].(type) {
+		case float64:
+			levels[strings.ToLower(key)] = float32(volume)
+		case float32:
+			levels[strings.ToLower(key)] = volume
+		case int:
+			levels[strings.ToLower(key)] = float32(volume)
+		}
+	}
+
+	return levels
+}
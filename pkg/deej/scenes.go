@@ -0,0 +1,284 @@
+package deej
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/omriharel/deej/pkg/deej/util"
+)
+
+// scene is a named snapshot of every session's volume and mute state, i.e. "meeting" or "night"
+type scene struct {
+	name   string
+	levels map[string]sceneLevel // by session key
+}
+
+type sceneLevel struct {
+	volume float32
+	muted  bool
+}
+
+const (
+
+	// fields of each session entry in a saved scene
+	sceneFieldSession = "session"
+	sceneFieldVolume  = "volume"
+	sceneFieldMuted   = "muted"
+)
+
+// saves the current volume and mute state of every session as a scene, replacing any scene of the same name
+func (m *sessionMap) saveScene(name string) error {
+	name = strings.ToLower(name)
+
+	// viper treats dots as key separators, which would split the scene in two when it's written
+	if name == "" || strings.Contains(name, ".") {
+		return fmt.Errorf("invalid scene name %q", name)
+	}
+
+	s := &scene{
+		name:   name,
+		levels: map[string]sceneLevel{},
+	}
+
+	m.useLock.Lock()
+	m.lock.Lock()
+
+	for key, sessions := range m.m {
+
+		// sessions sharing a key (such as a browser's many streams) are controlled together anyway
+		s.levels[key] = sceneLevel{
+			volume: util.NormalizeScalar(sessions[0].GetVolume()),
+			muted:  sessions[0].GetMute(),
+		}
+	}
+
+	m.lock.Unlock()
+	m.useLock.Unlock()
+
+	if err := m.deej.config.saveScene(s); err != nil {
+		m.logger.Warnw("Failed to save scene", "scene", name, "error", err)
+		return fmt.Errorf("save scene: %w", err)
+	}
+
+	m.logger.Infow("Saved scene", "scene", name, "sessions", len(s.levels))
+	m.deej.addTrayScene(name)
+
+	return nil
+}
+
+// restores a saved scene. sessions that aren't around right now get their levels once they show up
+func (m *sessionMap) restoreScene(name string) error {
+	s, ok := m.deej.config.scene(name)
+	if !ok {
+		return fmt.Errorf("no scene named %q", name)
+	}
+
+	m.logger.Infow("Restoring scene", "scene", s.name)
+
+	m.useLock.Lock()
+	defer m.useLock.Unlock()
+
+	m.pendingSceneLock.Lock()
+	defer m.pendingSceneLock.Unlock()
+
+	// a newly restored scene replaces whatever the previous one was still waiting on
+	m.pendingScene = map[string]sceneLevel{}
+
+	for key, level := range s.levels {
+		m.pendingScene[key] = level
+	}
+
+	m.applyPendingScene()
+
+	if len(m.pendingScene) > 0 {
+		m.logger.Debugw("Some of the scene's sessions aren't around, they'll be restored when they show up",
+			"sessions", len(m.pendingScene))
+	}
+
+	return nil
+}
+
+// applies the pending scene to any of its sessions that are currently around. assumes pendingSceneLock is held
+func (m *sessionMap) applyPendingScene() {
+	for key, level := range m.pendingScene {
+		sessions, ok := m.get(key)
+		if !ok {
+			continue
+		}
+
+		for _, session := range sessions {
+
+			// same as a slider setting the level: remembered, and kept down if the session is being ducked
+			m.rememberVolume(session, level.volume)
+			volume := m.duckVolume(session, level.volume)
+
+			if err := session.SetVolume(volume); err != nil {
+				m.logger.Warnw("Failed to restore session volume", "session", session, "error", err)
+			}
+
+			if session.GetMute() != level.muted {
+				if err := session.SetMute(level.muted); err != nil {
+					m.logger.Warnw("Failed to restore session mute state", "session", session, "error", err)
+				}
+			}
+		}
+
+		delete(m.pendingScene, key)
+	}
+}
+
+// saveScene stores a scene in the internal config and writes it to disk
+func (cc *CanonicalConfig) saveScene(s *scene) error {
+	cc.internalLock.Lock()
+	defer cc.internalLock.Unlock()
+
+	cc.Scenes[s.name] = s
+
+	serialized := map[string]interface{}{}
+	for name, s := range cc.Scenes {
+		serialized[name] = s.serialize()
+	}
+
+	cc.internalConfig.Set(configKeyScenes, serialized)
+
+	if err := cc.writeInternalConfig(); err != nil {
+		return fmt.Errorf("write internal config: %w", err)
+	}
+
+	return nil
+}
+
+// scene returns the saved scene of the given name, if there is one
+func (cc *CanonicalConfig) scene(name string) (*scene, bool) {
+	cc.internalLock.Lock()
+	defer cc.internalLock.Unlock()
+
+	s, ok := cc.Scenes[strings.ToLower(name)]
+
+	return s, ok
+}
+
+// sceneNames returns the names of every saved scene, sorted
+func (cc *CanonicalConfig) sceneNames() []string {
+	cc.internalLock.Lock()
+	defer cc.internalLock.Unlock()
+
+	names := make([]string, 0, len(cc.Scenes))
+	for name := range cc.Scenes {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// scenes are written as a list of entries rather than a map by session key,
+// as viper would split keys containing dots (i.e. "discord.exe") into nested maps
+func (s *scene) serialize() []interface{} {
+	keys := make([]string, 0, len(s.levels))
+	for key := range s.levels {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	entries := make([]interface{}, len(keys))
+	for keyIdx, key := range keys {
+		entries[keyIdx] = map[string]interface{}{
+			sceneFieldSession: key,
+			sceneFieldVolume:  s.levels[key].volume,
+			sceneFieldMuted:   s.levels[key].muted,
+		}
+	}
+
+	return entries
+}
+
+// scenesFromConfig parses the scenes saved in the internal config, skipping any that are malformed
+func scenesFromConfig(value map[string]interface{}) (map[string]*scene, error) {
+	scenes := map[string]*scene{}
+	var errs []string
+
+	for name, entries := range value {
+		s, err := parseScene(name, entries)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+
+		scenes[name] = s
+	}
+
+	if len(errs) > 0 {
+		return scenes, errors.New(strings.Join(errs, "; "))
+	}
+
+	return scenes, nil
+}
+
+func parseScene(name string, value interface{}) (*scene, error) {
+	entries, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("scene %q isn't a list", name)
+	}
+
+	s := &scene{
+		name:   name,
+		levels: map[string]sceneLevel{},
+	}
+
+	for _, entry := range entries {
+		fields, ok := toStringKeyedMap(entry)
+		if !ok {
+			return nil, fmt.Errorf("scene %q has an invalid entry", name)
+		}
+
+		key, _ := fields[sceneFieldSession].(string)
+		if key == "" {
+			return nil, fmt.Errorf("scene %q has an entry without a session", name)
+		}
+
+		var volume float32
+
+		switch typedVolume := fields[sceneFieldVolume].(type) {
+		case float64:
+			volume = float32(typedVolume)
+		case float32:
+			volume = typedVolume
+		case int:
+			volume = float32(typedVolume)
+		default:
+			return nil, fmt.Errorf("scene %q has an invalid volume for %s", name, key)
+		}
+
+		muted, _ := fields[sceneFieldMuted].(bool)
+
+		s.levels[strings.ToLower(key)] = sceneLevel{
+			volume: volume,
+			muted:  muted,
+		}
+	}
+
+	return s, nil
+}
+
+// toStringKeyedMap accepts maps as they come out of the yaml parser (keyed by interface{}) as well as viper's own
+func toStringKeyedMap(value interface{}) (map[string]interface{}, bool) {
+	switch typedValue := value.(type) {
+	case map[string]interface{}:
+		return typedValue, true
+
+	case map[interface{}]interface{}:
+		result := map[string]interface{}{}
+		for key, fieldValue := range typedValue {
+			result[fmt.Sprint(key)] = fieldValue
+		}
+
+		return result, true
+	}
+
+	return nil, false
+}
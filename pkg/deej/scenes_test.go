@@ -0,0 +1,137 @@
+package deej
+
+import (
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestRestoreSceneWhileDucked(t *testing.T) {
+	m := newTestSessionMap(t, &CanonicalConfig{
+		Scenes: map[string]*scene{
+			"night": {name: "night", levels: map[string]sceneLevel{
+				"game.exe":    {volume: 0.5},
+				"discord.exe": {volume: 0.7},
+			}},
+		},
+	})
+
+	game := &testSession{key: "game.exe", volume: 0.2}
+	chat := &testSession{key: "discord.exe", volume: 1}
+
+	for _, session := range []*testSession{game, chat} {
+		m.m[session.key] = []Session{session}
+	}
+
+	m.duckedKeys[game.key] = &duckedKey{level: 0.8, gain: 0.25}
+
+	if err := m.restoreScene("Night"); err != nil {
+		t.Fatalf("restore scene: %v", err)
+	}
+
+	// the game stays ducked, and goes to the scene's level once ducking lets go of it
+	if game.volume != 0.125 {
+		t.Errorf("expected the ducked game at 0.125, got %.3f", game.volume)
+	}
+
+	if level := m.duckedKeys[game.key].level; level != 0.5 {
+		t.Errorf("expected the game to go back to 0.5 after ducking, got %.2f", level)
+	}
+
+	if chat.volume != 0.7 {
+		t.Errorf("expected discord at 0.7, got %.2f", chat.volume)
+	}
+
+	m.restoreDuckedKeys()
+
+	if game.volume != 0.5 {
+		t.Errorf("expected the game back at 0.5 once ducking ended, got %.2f", game.volume)
+	}
+}
+
+func TestInitialSceneAfterSliderSync(t *testing.T) {
+	logger := zap.NewNop().Sugar()
+
+	sliderMapping, err := sliderMapFromConfigs(map[string]interface{}{
+		"0": "game.exe",
+		"1": "discord.exe",
+	}, nil)
+	if err != nil {
+		t.Fatalf("parse slider mapping: %v", err)
+	}
+
+	d := &Deej{
+		logger: logger,
+		config: &CanonicalConfig{
+			SliderMapping: sliderMapping,
+			Scenes: map[string]*scene{
+				"night": {name: "night", levels: map[string]sceneLevel{
+					"game.exe":    {volume: 0.3},
+					"discord.exe": {volume: 0.4},
+				}},
+			},
+		},
+		initialScene: "night",
+	}
+
+	d.serial = &SerialIO{deej: d, logger: logger}
+
+	game := &testSession{key: "game.exe", volume: 0.6}
+	chat := &testSession{key: "discord.exe", volume: 0.6}
+
+	m, err := newSessionMap(d, logger, &testSessionFinder{sessions: []Session{game, chat}})
+	if err != nil {
+		t.Fatalf("create session map: %v", err)
+	}
+
+	d.sessions = m
+
+	if err := m.getAndAddSessions(); err != nil {
+		t.Fatalf("get sessions: %v", err)
+	}
+
+	m.setupOnSliderMove()
+
+	// the deck's first line sets every slider, and the scene comes right after
+	d.serial.handleLine(logger, "1023|512\r\n")
+
+	waitForVolumes(t, m, map[*testSession]float32{game: 0.3, chat: 0.4})
+
+	// only once, after that sliders win as usual
+	d.serial.resendSliderValues()
+	d.serial.handleLine(logger, "0|512\r\n")
+
+	waitForVolumes(t, m, map[*testSession]float32{game: 0, chat: 0.5})
+}
+
+// waitForVolumes waits for the slider goroutine to put every session at its expected volume
+func waitForVolumes(t *testing.T, m *sessionMap, expected map[*testSession]float32) {
+	t.Helper()
+
+	reached := func() bool {
+		m.useLock.Lock()
+		defer m.useLock.Unlock()
+
+		for session, volume := range expected {
+			if session.volume != volume {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	for deadline := time.Now().Add(time.Second); !reached(); time.Sleep(5 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			m.useLock.Lock()
+			defer m.useLock.Unlock()
+
+			for session, volume := range expected {
+				t.Errorf("expected %s at %.2f, got %.2f", session.key, volume, session.volume)
+			}
+
+			return
+		}
+	}
+}
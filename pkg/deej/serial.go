@@ -8,6 +8,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/jacobsa/go-serial/serial"
@@ -32,7 +33,17 @@ type SerialIO struct {
 	lastKnownNumSliders        int
 	currentSliderPercentValues []float32
 
+	// set when every slider's position is about to be sent, until it has been
+	sliderSyncPending bool
+
 	sliderMoveConsumers []chan SliderMoveEvent
+	sliderSyncConsumers []chan bool
+
+	// a short message for the deck's display, sent along with the system data until it expires
+	displayMessage       string
+	displayMessageExpiry time.Time
+	displayStatus        string // shown whenever there's no message
+	displayMessageLock   sync.Mutex
 
 	brightnessController *BrightnessController
 	keyboardController   *KeyboardController
@@ -62,8 +73,9 @@ func NewSerialIO(deej *Deej, logger *zap.SugaredLogger) (*SerialIO, error) {
 		connected:            false,
 		conn:                 nil,
 		sliderMoveConsumers:  []chan SliderMoveEvent{},
-		brightnessController: NewBrightnessController(),
-		keyboardController:   NewKeyboardController(logger),
+		sliderSyncConsumers:  []chan bool{},
+		brightnessController: NewBrightnessController(deej, logger),
+		keyboardController:   NewKeyboardController(deej, logger),
 	}
 
 	logger.Debug("Created serial i/o instance")
@@ -158,6 +170,22 @@ func (sio *SerialIO) SubscribeToSliderMoveEvents() chan SliderMoveEvent {
 	return ch
 }
 
+// SubscribeToSliderSyncs returns an unbuffered channel that receives a value every time all sliders' positions
+// were sent (after connecting, and whenever they're resent). it's sent to after the move events themselves,
+// so a consumer reading both channels from one goroutine has handled every one of them by the time it gets it
+func (sio *SerialIO) SubscribeToSliderSyncs() chan bool {
+	ch := make(chan bool)
+	sio.sliderSyncConsumers = append(sio.sliderSyncConsumers, ch)
+
+	return ch
+}
+
+// resendSliderValues makes the next read line emit SliderMoveEvent instances for all sliders,
+// so their current levels get applied again
+func (sio *SerialIO) resendSliderValues() {
+	sio.lastKnownNumSliders = 0
+}
+
 func (sio *SerialIO) setupOnConfigReload() {
 	configReloadedChannel := sio.deej.config.SubscribeToChanges()
 
@@ -175,9 +203,12 @@ func (sio *SerialIO) setupOnConfigReload() {
 				// is still cleared. this is kind of ugly, but shouldn't cause any issues
 				go func() {
 					<-time.After(stopDelay)
-					sio.lastKnownNumSliders = 0
+					sio.resendSliderValues()
 				}()
 
+				// the key mappings may have changed, so the next line should check for unmapped keys again
+				sio.keyboardController.recheckKeys()
+
 				// if connection params have changed, attempt to stop and start the connection
 				if sio.deej.config.ConnectionInfo.COMPort != sio.connOptions.PortName ||
 					uint(sio.deej.config.ConnectionInfo.BaudRate) != sio.connOptions.BaudRate {
@@ -273,6 +304,7 @@ func (sio *SerialIO) handleLine(logger *zap.SugaredLogger, line string) {
 	if numSliders != sio.lastKnownNumSliders {
 		logger.Infow("Detected sliders", "amount", numSliders)
 		sio.lastKnownNumSliders = numSliders
+		sio.sliderSyncPending = true
 		sio.currentSliderPercentValues = make([]float32, numSliders)
 
 		// reset everything to be an impossible value to force the slider move event later
@@ -332,13 +364,24 @@ func (sio *SerialIO) handleLine(logger *zap.SugaredLogger, line string) {
 		}
 	}
 
+	// let consumers know once every slider's position went out
+	if sio.sliderSyncPending {
+		sio.sliderSyncPending = false
+
+		for _, consumer := range sio.sliderSyncConsumers {
+			consumer <- true
+		}
+	}
+
 	// If there are additional parts, handle brightness control
 	if len(lineParts) > 3 {
 		sio.brightnessController.HandleBrightnessInfo(lineParts[3])
 	}
 
-	// If there are additional parts, handle brightness control
+	// If there are additional parts, handle the macro keys
 	if len(lineParts) > 4 {
-		sio.keyboardController.HandleKeyboardInfo(lineParts[4])
+		if err := sio.keyboardController.HandleKeyboardInfo(lineParts[4]); err != nil {
+			logger.Debugw("Failed to handle keyboard info", "error", err)
+		}
 	}
 }
@@ -2,6 +2,7 @@ package deej
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/shirou/gopsutil/cpu"
@@ -41,8 +42,9 @@ func (sio *SerialIO) SendSystemData() {
 					continue
 				}
 
-				// Format the system data string
-				systemDataString := fmt.Sprintf("%s|CPU: %2.0f%%\r\n", currentTime, percentages[0])
+				// Format the system data string, along with any message the display should currently show
+				systemDataString := fmt.Sprintf("%s|CPU: %2.0f%%|%s\r\n",
+					currentTime, percentages[0], sio.currentDisplayMessage())
 
 				// Send the CPU load and time string
 				if _, err := sio.conn.Write([]byte(systemDataString)); err != nil {
@@ -54,3 +56,38 @@ func (sio *SerialIO) SendSystemData() {
 		}
 	}()
 }
+
+// how long a message stays on the deck's display
+const displayMessageDuration = 5 * time.Second
+
+// messages travel inside a "|"-separated line, so they can't contain separators or line breaks
+var displayMessageReplacer = strings.NewReplacer("|", " ", "\r", " ", "\n", " ")
+
+// ShowMessage has the deck's display show a short message (such as a newly selected audio device) for a few seconds
+func (sio *SerialIO) ShowMessage(message string) {
+	sio.displayMessageLock.Lock()
+	defer sio.displayMessageLock.Unlock()
+
+	sio.displayMessage = displayMessageReplacer.Replace(message)
+	sio.displayMessageExpiry = time.Now().Add(displayMessageDuration)
+}
+
+// SetDisplayStatus has the deck's display keep showing a status (such as the current key layer) whenever it isn't
+// showing a message. an empty status clears it
+func (sio *SerialIO) SetDisplayStatus(status string) {
+	sio.displayMessageLock.Lock()
+	defer sio.displayMessageLock.Unlock()
+
+	sio.displayStatus = displayMessageReplacer.Replace(status)
+}
+
+func (sio *SerialIO) currentDisplayMessage() string {
+	sio.displayMessageLock.Lock()
+	defer sio.displayMessageLock.Unlock()
+
+	if time.Now().After(sio.displayMessageExpiry) {
+		return sio.displayStatus
+	}
+
+	return sio.displayMessage
+}
@@ -1,6 +1,7 @@
 package deej
 
 import (
+	"errors"
 	"strings"
 
 	"go.uber.org/zap"
@@ -11,14 +12,44 @@ type Session interface {
 	GetVolume() float32
 	SetVolume(v float32) error
 
-	// TODO: future mute support
-	// GetMute() bool
-	// SetMute(m bool) error
+	GetMute() bool
+	SetMute(m bool) error
 
 	Key() string
+	Property(name string) (string, bool)
 	Release()
 }
 
+// movableSession is implemented by playback sessions that can be moved to another output device (Linux-only for now)
+type movableSession interface {
+	Session
+
+	// false for recording streams, which share the type but have no output device to be moved off of
+	Playback() bool
+
+	OutputDevice() (string, error)
+	MoveToOutputDevice(deviceName string) error
+}
+
+// activeSession is implemented by sessions that can tell whether they're currently playing (or recording) audio
+type activeSession interface {
+	Session
+
+	Active() bool
+}
+
+// returned by balanced sessions that don't have a balance to control (such as recording streams, or mono devices)
+var errBalanceUnsupported = errors.New("Session has no balance to control")
+
+// balancedSession is implemented by sessions whose left/right balance can be controlled (Linux-only for now)
+type balancedSession interface {
+	Session
+
+	// 0 is all the way to the left, 0.5 is centered and 1 is all the way to the right
+	GetBalance() (float32, error)
+	SetBalance(b float32) error
+}
+
 const (
 
 	// ideally these would share a common ground in baseSession
@@ -27,6 +58,16 @@ const (
 
 	// format this with s.humanReadableDesc and whatever the current volume is
 	sessionStringFormat = "<session: %s, vol: %.2f>"
+
+	// well-known session properties. these follow PulseAudio's naming, other platforms fill in what they can
+	propertyApplicationName = "application.name"
+	propertyProcessBinary   = "application.process.binary"
+	propertyProcessID       = "application.process.id"
+	propertyMediaName       = "media.name"
+	propertyMediaRole       = "media.role"
+
+	// devices have properties too
+	propertyDeviceDescription = "device.description"
 )
 
 type baseSession struct {
@@ -39,6 +80,9 @@ type baseSession struct {
 
 	// used by String(), needs to be set by child
 	humanReadableDesc string
+
+	// used by Property(), optionally set by child
+	properties map[string]string
 }
 
 func (s *baseSession) Key() string {
@@ -52,3 +96,8 @@ func (s *baseSession) Key() string {
 
 	return strings.ToLower(s.name)
 }
+
+func (s *baseSession) Property(name string) (string, bool) {
+	value, ok := s.properties[name]
+	return value, ok
+}
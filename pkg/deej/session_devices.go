@@ -0,0 +1,300 @@
+package deej
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var errDeviceControlUnsupported = errors.New("controlling audio devices isn't supported on this platform")
+
+// moves every playback stream matching the target to the given output device
+func (m *sessionMap) moveStreams(target string, deviceQuery string) (audioDevice, error) {
+	m.useLock.Lock()
+	defer m.useLock.Unlock()
+
+	finder, err := m.deviceFinder()
+	if err != nil {
+		return audioDevice{}, err
+	}
+
+	devices, err := finder.GetOutputDevices()
+	if err != nil {
+		return audioDevice{}, fmt.Errorf("get output devices: %w", err)
+	}
+
+	device, ok := findAudioDevice(devices, deviceQuery)
+	if !ok {
+		return audioDevice{}, fmt.Errorf("no output device matches %q", deviceQuery)
+	}
+
+	sessions := m.movableSessionsForTarget(target)
+	if len(sessions) == 0 {
+		return audioDevice{}, fmt.Errorf("no playback streams match %q", target)
+	}
+
+	return device, m.moveSessionsToDevice(sessions, device)
+}
+
+// moves every playback stream matching the target to the output device that comes after the one it's currently on.
+// if no devices are given, all of the system's output devices take part in the cycle
+func (m *sessionMap) cycleStreams(target string, deviceQueries []string) (audioDevice, error) {
+	m.useLock.Lock()
+	defer m.useLock.Unlock()
+
+	finder, err := m.deviceFinder()
+	if err != nil {
+		return audioDevice{}, err
+	}
+
+	devices, err := finder.GetOutputDevices()
+	if err != nil {
+		return audioDevice{}, fmt.Errorf("get output devices: %w", err)
+	}
+
+	candidates := m.findAudioDevices(devices, deviceQueries)
+	if len(candidates) == 0 {
+		return audioDevice{}, errors.New("no output devices to cycle through")
+	}
+
+	sessions := m.movableSessionsForTarget(target)
+	if len(sessions) == 0 {
+		return audioDevice{}, fmt.Errorf("no playback streams match %q", target)
+	}
+
+	// the first stream decides where we are in the cycle, the rest just follow along
+	currentDevice, err := sessions[0].OutputDevice()
+	if err != nil {
+		return audioDevice{}, fmt.Errorf("get current output device: %w", err)
+	}
+
+	device := nextAudioDevice(candidates, currentDevice)
+
+	return device, m.moveSessionsToDevice(sessions, device)
+}
+
+// makes the given device the system's default output (or input) device. master (or mic) follow along right away
+func (m *sessionMap) setDefaultDevice(output bool, deviceQuery string, moveStreams bool) (audioDevice, error) {
+	m.useLock.Lock()
+	defer m.useLock.Unlock()
+
+	finder, err := m.deviceFinder()
+	if err != nil {
+		return audioDevice{}, err
+	}
+
+	devices, err := m.devices(finder, output)
+	if err != nil {
+		return audioDevice{}, err
+	}
+
+	device, ok := findAudioDevice(devices, deviceQuery)
+	if !ok {
+		return audioDevice{}, fmt.Errorf("no device matches %q", deviceQuery)
+	}
+
+	return device, m.switchDefaultDevice(finder, output, device, moveStreams)
+}
+
+// makes the device that comes after the current default output (or input) device the new default.
+// if no devices are given, all of the system's devices of that kind take part in the cycle
+func (m *sessionMap) cycleDefaultDevice(output bool, deviceQueries []string, moveStreams bool) (audioDevice, error) {
+	m.useLock.Lock()
+	defer m.useLock.Unlock()
+
+	finder, err := m.deviceFinder()
+	if err != nil {
+		return audioDevice{}, err
+	}
+
+	devices, err := m.devices(finder, output)
+	if err != nil {
+		return audioDevice{}, err
+	}
+
+	candidates := m.findAudioDevices(devices, deviceQueries)
+	if len(candidates) == 0 {
+		return audioDevice{}, errors.New("no devices to cycle through")
+	}
+
+	defaultOutput, defaultInput, err := finder.GetDefaultDevices()
+	if err != nil {
+		return audioDevice{}, fmt.Errorf("get default devices: %w", err)
+	}
+
+	currentDevice := defaultInput
+	if output {
+		currentDevice = defaultOutput
+	}
+
+	device := nextAudioDevice(candidates, currentDevice)
+
+	return device, m.switchDefaultDevice(finder, output, device, moveStreams)
+}
+
+// assumes useLock is held
+func (m *sessionMap) switchDefaultDevice(finder deviceFinder, output bool, device audioDevice, moveStreams bool) error {
+	m.logger.Infow("Switching default device", "device", device, "output", output, "moveStreams", moveStreams)
+
+	var err error
+
+	if output {
+		err = finder.SetDefaultOutputDevice(device.name, moveStreams)
+	} else {
+		err = finder.SetDefaultInputDevice(device.name, moveStreams)
+	}
+
+	if err != nil {
+		m.logger.Warnw("Failed to switch default device", "device", device, "error", err)
+		return fmt.Errorf("switch default device: %w", err)
+	}
+
+	// performance: forcing a refresh is fine here, as this only happens when the user asks for a device switch.
+	// master and mic point at the old device until we do
+	m.refreshSessionsLocked(true)
+
+	// have the sliders re-apply their levels, so the new device follows master (or mic) right away
+	m.deej.serial.resendSliderValues()
+
+	return nil
+}
+
+func (m *sessionMap) moveSessionsToDevice(sessions []movableSession, device audioDevice) error {
+	m.logger.Infow("Moving playback streams to output device", "device", device, "streams", len(sessions))
+
+	moveFailed := false
+
+	for _, session := range sessions {
+		if err := session.MoveToOutputDevice(device.name); err != nil {
+			m.logger.Warnw("Failed to move playback stream", "session", session, "error", err)
+			moveFailed = true
+		}
+	}
+
+	if moveFailed {
+		return fmt.Errorf("move playback streams to %s", device.name)
+	}
+
+	return nil
+}
+
+func (m *sessionMap) deviceFinder() (deviceFinder, error) {
+	finder, ok := m.sessionFinder.(deviceFinder)
+	if !ok {
+		return nil, errDeviceControlUnsupported
+	}
+
+	return finder, nil
+}
+
+func (m *sessionMap) devices(finder deviceFinder, output bool) ([]audioDevice, error) {
+	var devices []audioDevice
+	var err error
+
+	if output {
+		devices, err = finder.GetOutputDevices()
+	} else {
+		devices, err = finder.GetInputDevices()
+	}
+
+	if err != nil {
+		m.logger.Warnw("Failed to get audio devices", "output", output, "error", err)
+		return nil, fmt.Errorf("get audio devices: %w", err)
+	}
+
+	return devices, nil
+}
+
+// returns the devices matching the given queries, in order. with no queries, all devices are returned
+func (m *sessionMap) findAudioDevices(devices []audioDevice, queries []string) []audioDevice {
+	if len(queries) == 0 {
+		return devices
+	}
+
+	found := []audioDevice{}
+
+	for _, query := range queries {
+		device, ok := findAudioDevice(devices, query)
+		if !ok {
+			m.logger.Warnw("Skipping unknown audio device", "device", query)
+			continue
+		}
+
+		found = append(found, device)
+	}
+
+	return found
+}
+
+// returns the playback sessions matching a target, leaving out recording streams (which wide targets like globs
+// also match). if there are none, the app could've just started,
+// so look for it again (the refresh cooldown keeps this from getting spammy). assumes useLock is held
+func (m *sessionMap) movableSessionsForTarget(target string) []movableSession {
+	find := func() []movableSession {
+		sessions := []movableSession{}
+
+		for _, session := range m.sessionsForTarget(target) {
+			if movable, ok := session.(movableSession); ok && movable.Playback() {
+				sessions = append(sessions, movable)
+			}
+		}
+
+		return sessions
+	}
+
+	sessions := find()
+	if len(sessions) == 0 {
+		m.refreshSessionsLocked(false)
+		sessions = find()
+	}
+
+	return sessions
+}
+
+// findAudioDevice looks a device up by its system name or by its description. as descriptions tend to be
+// long-winded ("USB Headset Analog Stereo"), any device whose description contains the query will also do
+func findAudioDevice(devices []audioDevice, query string) (audioDevice, bool) {
+	for _, device := range devices {
+		if strings.EqualFold(device.name, query) || strings.EqualFold(device.description, query) {
+			return device, true
+		}
+	}
+
+	lowerQuery := strings.ToLower(query)
+
+	for _, device := range devices {
+		if strings.Contains(strings.ToLower(device.description), lowerQuery) {
+			return device, true
+		}
+	}
+
+	return audioDevice{}, false
+}
+
+// nextAudioDevice returns the device following the current one, or the first one if the current device isn't listed
+func nextAudioDevice(devices []audioDevice, currentDeviceName string) audioDevice {
+	for deviceIdx, device := range devices {
+		if device.name == currentDeviceName {
+			return devices[(deviceIdx+1)%len(devices)]
+		}
+	}
+
+	return devices[0]
+}
+
+func (d audioDevice) String() string {
+	if d.description == "" {
+		return d.name
+	}
+
+	return fmt.Sprintf("%s (%s)", d.description, d.name)
+}
+
+// displayName is a short name for the device, fit for the deck's display
+func (d audioDevice) displayName() string {
+	if d.description == "" {
+		return d.name
+	}
+
+	return d.description
+}
@@ -6,3 +6,21 @@ type SessionFinder interface {
 
 	Release() error
 }
+
+// deviceFinder is implemented by session finders that can list and switch the system's audio devices (Linux-only for now)
+type deviceFinder interface {
+	GetOutputDevices() ([]audioDevice, error)
+	GetInputDevices() ([]audioDevice, error)
+
+	// returns the names of the default output and input devices
+	GetDefaultDevices() (string, string, error)
+
+	SetDefaultOutputDevice(deviceName string, moveStreams bool) error
+	SetDefaultInputDevice(deviceName string, moveStreams bool) error
+}
+
+// audioDevice identifies an audio device by its system name, along with a description fit for humans
+type audioDevice struct {
+	name        string
+	description string
+}
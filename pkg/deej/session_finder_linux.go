@@ -85,6 +85,146 @@ func (sf *paSessionFinder) Release() error {
 	return nil
 }
 
+func (sf *paSessionFinder) GetOutputDevices() ([]audioDevice, error) {
+	request := proto.GetSinkInfoList{}
+	reply := proto.GetSinkInfoListReply{}
+
+	if err := sf.client.Request(&request, &reply); err != nil {
+		sf.logger.Warnw("Failed to get sink list", "error", err)
+		return nil, fmt.Errorf("get sink list: %w", err)
+	}
+
+	devices := make([]audioDevice, 0, len(reply))
+
+	for _, info := range reply {
+		devices = append(devices, audioDevice{
+			name:        info.SinkName,
+			description: stringProperties(info.Properties)[propertyDeviceDescription],
+		})
+	}
+
+	return devices, nil
+}
+
+func (sf *paSessionFinder) GetInputDevices() ([]audioDevice, error) {
+	request := proto.GetSourceInfoList{}
+	reply := proto.GetSourceInfoListReply{}
+
+	if err := sf.client.Request(&request, &reply); err != nil {
+		sf.logger.Warnw("Failed to get source list", "error", err)
+		return nil, fmt.Errorf("get source list: %w", err)
+	}
+
+	devices := make([]audioDevice, 0, len(reply))
+
+	for _, info := range reply {
+
+		// every sink comes with a monitor source, those aren't the microphones anyone is looking for
+		if info.MonitorSourceIndex != proto.Undefined {
+			continue
+		}
+
+		devices = append(devices, audioDevice{
+			name:        info.SourceName,
+			description: stringProperties(info.Properties)[propertyDeviceDescription],
+		})
+	}
+
+	return devices, nil
+}
+
+func (sf *paSessionFinder) GetDefaultDevices() (string, string, error) {
+	request := proto.GetServerInfo{}
+	reply := proto.GetServerInfoReply{}
+
+	if err := sf.client.Request(&request, &reply); err != nil {
+		sf.logger.Warnw("Failed to get server info", "error", err)
+		return "", "", fmt.Errorf("get server info: %w", err)
+	}
+
+	return reply.DefaultSinkName, reply.DefaultSourceName, nil
+}
+
+func (sf *paSessionFinder) SetDefaultOutputDevice(deviceName string, moveStreams bool) error {
+	request := proto.SetDefaultSink{
+		SinkName: deviceName,
+	}
+
+	if err := sf.client.Request(&request, nil); err != nil {
+		sf.logger.Warnw("Failed to set default sink", "error", err, "sink", deviceName)
+		return fmt.Errorf("set default sink: %w", err)
+	}
+
+	if !moveStreams {
+		return nil
+	}
+
+	// streams that were explicitly routed somewhere stay there unless we move them ourselves
+	streams := proto.GetSinkInputInfoListReply{}
+
+	if err := sf.client.Request(&proto.GetSinkInputInfoList{}, &streams); err != nil {
+		sf.logger.Warnw("Failed to get sink input list", "error", err)
+		return fmt.Errorf("get sink input list: %w", err)
+	}
+
+	for _, info := range streams {
+		request := proto.MoveSinkInput{
+			SinkInputIndex: info.SinkInputIndex,
+			DeviceIndex:    proto.Undefined,
+			DeviceName:     deviceName,
+		}
+
+		// some streams refuse to move (they're pinned to their device), that's fine
+		if err := sf.client.Request(&request, nil); err != nil {
+			sf.logger.Debugw("Failed to move sink input to new default sink",
+				"sinkInputIndex", info.SinkInputIndex,
+				"error", err)
+		}
+	}
+
+	return nil
+}
+
+func (sf *paSessionFinder) SetDefaultInputDevice(deviceName string, moveStreams bool) error {
+	request := proto.SetDefaultSource{
+		SourceName: deviceName,
+	}
+
+	if err := sf.client.Request(&request, nil); err != nil {
+		sf.logger.Warnw("Failed to set default source", "error", err, "source", deviceName)
+		return fmt.Errorf("set default source: %w", err)
+	}
+
+	if !moveStreams {
+		return nil
+	}
+
+	// streams that were explicitly routed somewhere stay there unless we move them ourselves
+	streams := proto.GetSourceOutputInfoListReply{}
+
+	if err := sf.client.Request(&proto.GetSourceOutputInfoList{}, &streams); err != nil {
+		sf.logger.Warnw("Failed to get source output list", "error", err)
+		return fmt.Errorf("get source output list: %w", err)
+	}
+
+	for _, info := range streams {
+		request := proto.MoveSourceOutput{
+			SourceOutputIndex: info.SourceOutpuIndex,
+			DeviceIndex:       proto.Undefined,
+			DeviceName:        deviceName,
+		}
+
+		// some streams refuse to move (they're pinned to their device), that's fine
+		if err := sf.client.Request(&request, nil); err != nil {
+			sf.logger.Debugw("Failed to move source output to new default source",
+				"sourceOutputIndex", info.SourceOutpuIndex,
+				"error", err)
+		}
+	}
+
+	return nil
+}
+
 func (sf *paSessionFinder) getMasterSinkSession() (Session, error) {
 	request := proto.GetSinkInfo{
 		SinkIndex: proto.Undefined,
@@ -129,8 +269,9 @@ func (sf *paSessionFinder) enumerateAndAddSessions(sessions *[]Session) error {
 	}
 
 	for _, info := range reply {
-		name, ok := info.Properties["application.process.binary"]
+		properties := stringProperties(info.Properties)
 
+		name, ok := sessionNameFromProperties(properties)
 		if !ok {
 			sf.logger.Warnw("Failed to get sink input's process name",
 				"sinkInputIndex", info.SinkInputIndex)
@@ -139,12 +280,84 @@ func (sf *paSessionFinder) enumerateAndAddSessions(sessions *[]Session) error {
 		}
 
 		// create the deej session object
-		newSession := newPASession(sf.sessionLogger, sf.client, info.SinkInputIndex, info.Channels, name.String())
+		newSession := newPASession(sf.sessionLogger, sf.client, info.SinkInputIndex, info.Channels, name, properties, true)
 
 		// add it to our slice
 		*sessions = append(*sessions, newSession)
 
 	}
 
+	// recording apps (voice chat, streaming software, browser calls) show up as source outputs.
+	// playback sessions are still good without them, so don't throw those away over it
+	if err := sf.enumerateAndAddSourceOutputSessions(sessions); err != nil {
+		sf.logger.Warnw("Failed to enumerate recording sessions, continuing without them", "error", err)
+	}
+
+	return nil
+}
+
+func (sf *paSessionFinder) enumerateAndAddSourceOutputSessions(sessions *[]Session) error {
+	request := proto.GetSourceOutputInfoList{}
+	reply := proto.GetSourceOutputInfoListReply{}
+
+	if err := sf.client.Request(&request, &reply); err != nil {
+		sf.logger.Warnw("Failed to get source output list", "error", err)
+		return fmt.Errorf("get source output list: %w", err)
+	}
+
+	for _, info := range reply {
+		properties := stringProperties(info.Properties)
+
+		name, ok := sessionNameFromProperties(properties)
+		if !ok {
+			sf.logger.Warnw("Failed to get source output's process name",
+				"sourceOutputIndex", info.SourceOutpuIndex)
+
+			continue
+		}
+
+		// some streams (i.e. level meters) don't allow changing their volume, there's nothing for us to do with those
+		if !info.VolumeWritable {
+			sf.logger.Debugw("Skipping source output with read-only volume",
+				"sourceOutputIndex", info.SourceOutpuIndex,
+				"processName", name)
+
+			continue
+		}
+
+		// create the deej session object
+		newSession := newPASession(sf.sessionLogger, sf.client, info.SourceOutpuIndex, info.Channels, name, properties, false)
+
+		// add it to our slice
+		*sessions = append(*sessions, newSession)
+	}
+
 	return nil
 }
+
+// stringProperties flattens a PulseAudio property list into plain strings, dropping any binary values
+func stringProperties(propList proto.PropList) map[string]string {
+	properties := make(map[string]string, len(propList))
+
+	for name, entry := range propList {
+		if len(entry) == 0 || entry[len(entry)-1] != '\x00' {
+			continue
+		}
+
+		properties[name] = entry.String()
+	}
+
+	return properties
+}
+
+// sessionNameFromProperties picks the name a stream's session will be keyed by. the binary name is preferred,
+// but sandboxed apps (flatpak) and some wine games don't report one, so fall back to whatever else they tell us
+func sessionNameFromProperties(properties map[string]string) (string, bool) {
+	for _, property := range []string{propertyProcessBinary, propertyApplicationName, propertyMediaName} {
+		if name, ok := properties[property]; ok && name != "" {
+			return name, true
+		}
+	}
+
+	return "", false
+}
@@ -9,10 +9,18 @@ import (
 	"github.com/jfreymuth/pulse/proto"
 )
 
-// normal PulseAudio volume (100%)
-const maxVolume = 0x10000
+const (
+
+	// normal PulseAudio volume (100%)
+	maxVolume = 0x10000
+
+	// the most PulseAudio's own volume controls let you boost to (+11dB, about 153%). going further is possible,
+	// but only distorts the sound, so boosted volumes are capped here
+	maxBoostedVolume = 99957
+)
 
 var errNoSuchProcess = errors.New("No such process")
+var errNotPlaybackSession = errors.New("Recording streams can't be moved to an output device")
 
 type paSession struct {
 	baseSession
@@ -21,8 +29,11 @@ type paSession struct {
 
 	client *proto.Client
 
-	sinkInputIndex    uint32
-	sinkInputChannels byte
+	streamIndex    uint32
+	streamChannels byte
+	isOutput       bool // sink input (playback) when true, source output (recording) otherwise
+
+	channelBalance
 }
 
 type masterSession struct {
@@ -33,26 +44,44 @@ type masterSession struct {
 	streamIndex    uint32
 	streamChannels byte
 	isOutput       bool
+
+	channelBalance
+}
+
+// channelBalance remembers the ratio between a stream's channels, so it can be kept intact when scaling the
+// stream's volume. the last audible channel volumes are kept around, as a silent stream has no ratio to speak of
+type channelBalance struct {
+	lastChannelVolumes proto.ChannelVolumes
 }
 
 func newPASession(
 	logger *zap.SugaredLogger,
 	client *proto.Client,
-	sinkInputIndex uint32,
-	sinkInputChannels byte,
+	streamIndex uint32,
+	streamChannels byte,
 	processName string,
+	properties map[string]string,
+	isOutput bool,
 ) *paSession {
 
 	s := &paSession{
-		client:            client,
-		sinkInputIndex:    sinkInputIndex,
-		sinkInputChannels: sinkInputChannels,
+		client:         client,
+		streamIndex:    streamIndex,
+		streamChannels: streamChannels,
+		isOutput:       isOutput,
 	}
 
 	s.processName = processName
+	s.properties = properties
 	s.name = processName
 	s.humanReadableDesc = processName
 
+	// recording streams get a distinguishable key (e.g. "input:discord") so they don't collide with playback
+	if !isOutput {
+		s.name = inputSessionPrefix + processName
+		s.humanReadableDesc = fmt.Sprintf("%s (recording)", processName)
+	}
+
 	// use a self-identifying session name e.g. deej.sessions.chrome
 	s.logger = logger.Named(s.Key())
 	s.logger.Debugw(sessionCreationLogMessage, "session", s)
@@ -94,28 +123,26 @@ func newMasterSession(
 }
 
 func (s *paSession) GetVolume() float32 {
-	request := proto.GetSinkInputInfo{
-		SinkInputIndex: s.sinkInputIndex,
-	}
-	reply := proto.GetSinkInputInfoReply{}
-
-	if err := s.client.Request(&request, &reply); err != nil {
+	_, volumes, err := s.channels()
+	if err != nil {
 		s.logger.Warnw("Failed to get session volume", "error", err)
 	}
 
-	level := parseChannelVolumes(reply.ChannelVolumes)
+	level := parseChannelVolumes(volumes)
 
 	return level
 }
 
 func (s *paSession) SetVolume(v float32) error {
-	volumes := createChannelVolumes(s.sinkInputChannels, v)
-	request := proto.SetSinkInputVolume{
-		SinkInputIndex: s.sinkInputIndex,
-		ChannelVolumes: volumes,
+	_, currentVolumes, err := s.channels()
+	if err != nil {
+		s.logger.Warnw("Failed to get session channel volumes, resetting its balance", "error", err)
 	}
 
-	if err := s.client.Request(&request, nil); err != nil {
+	v = clampVolume(v)
+	volumes := s.scaleChannelVolumes(currentVolumes, s.streamChannels, v)
+
+	if err := s.setChannelVolumes(volumes); err != nil {
 		s.logger.Warnw("Failed to set session volume", "error", err)
 		return fmt.Errorf("adjust session volume: %w", err)
 	}
@@ -125,6 +152,229 @@ func (s *paSession) SetVolume(v float32) error {
 	return nil
 }
 
+func (s *paSession) GetBalance() (float32, error) {
+	if !s.isOutput {
+		return 0, errBalanceUnsupported
+	}
+
+	channelMap, volumes, err := s.channels()
+	if err != nil {
+		return 0, fmt.Errorf("get session channel volumes: %w", err)
+	}
+
+	return parseChannelBalance(channelMap, volumes)
+}
+
+func (s *paSession) SetBalance(b float32) error {
+	if !s.isOutput {
+		return errBalanceUnsupported
+	}
+
+	channelMap, currentVolumes, err := s.channels()
+	if err != nil {
+		s.logger.Warnw("Failed to get session channel volumes", "error", err)
+		return fmt.Errorf("get session channel volumes: %w", err)
+	}
+
+	volumes, err := s.balanceChannelVolumes(channelMap, currentVolumes, b)
+	if err != nil {
+		return err
+	}
+
+	if err := s.setChannelVolumes(volumes); err != nil {
+		s.logger.Warnw("Failed to set session balance", "error", err)
+		return fmt.Errorf("adjust session balance: %w", err)
+	}
+
+	s.logger.Debugw("Adjusting session balance", "to", fmt.Sprintf("%.2f", b))
+
+	return nil
+}
+
+// channels returns the stream's channel map along with each channel's volume
+func (s *paSession) channels() (proto.ChannelMap, proto.ChannelVolumes, error) {
+	if s.isOutput {
+		request := proto.GetSinkInputInfo{
+			SinkInputIndex: s.streamIndex,
+		}
+		reply := proto.GetSinkInputInfoReply{}
+
+		if err := s.client.Request(&request, &reply); err != nil {
+			return nil, nil, fmt.Errorf("get sink input info: %w", err)
+		}
+
+		return reply.ChannelMap, reply.ChannelVolumes, nil
+	}
+
+	request := proto.GetSourceOutputInfo{
+		SourceOutpuIndex: s.streamIndex,
+	}
+	reply := proto.GetSourceOutputInfoReply{}
+
+	if err := s.client.Request(&request, &reply); err != nil {
+		return nil, nil, fmt.Errorf("get source output info: %w", err)
+	}
+
+	return reply.ChannelMap, reply.ChannelVolumes, nil
+}
+
+func (s *paSession) setChannelVolumes(volumes proto.ChannelVolumes) error {
+	var request proto.RequestArgs
+
+	if s.isOutput {
+		request = &proto.SetSinkInputVolume{
+			SinkInputIndex: s.streamIndex,
+			ChannelVolumes: volumes,
+		}
+	} else {
+		request = &proto.SetSourceOutputVolume{
+			SourceOutputIndex: s.streamIndex,
+			ChannelVolumes:    volumes,
+		}
+	}
+
+	return s.client.Request(request, nil)
+}
+
+func (s *paSession) GetMute() bool {
+	if s.isOutput {
+		request := proto.GetSinkInputInfo{
+			SinkInputIndex: s.streamIndex,
+		}
+		reply := proto.GetSinkInputInfoReply{}
+
+		if err := s.client.Request(&request, &reply); err != nil {
+			s.logger.Warnw("Failed to get session mute state", "error", err)
+			return false
+		}
+
+		return reply.Muted
+	}
+
+	request := proto.GetSourceOutputInfo{
+		SourceOutpuIndex: s.streamIndex,
+	}
+	reply := proto.GetSourceOutputInfoReply{}
+
+	if err := s.client.Request(&request, &reply); err != nil {
+		s.logger.Warnw("Failed to get session mute state", "error", err)
+		return false
+	}
+
+	return reply.Muted
+}
+
+func (s *paSession) SetMute(m bool) error {
+	var request proto.RequestArgs
+
+	if s.isOutput {
+		request = &proto.SetSinkInputMute{
+			SinkInputIndex: s.streamIndex,
+			Mute:           m,
+		}
+	} else {
+		request = &proto.SetSourceOutputMute{
+			SourceOutputIndex: s.streamIndex,
+			Mute:              m,
+		}
+	}
+
+	if err := s.client.Request(request, nil); err != nil {
+		s.logger.Warnw("Failed to set session mute state", "error", err)
+		return fmt.Errorf("adjust session mute state: %w", err)
+	}
+
+	s.logger.Debugw("Adjusting session mute state", "to", m)
+
+	return nil
+}
+
+// Active returns true while the stream isn't corked (paused)
+func (s *paSession) Active() bool {
+	var corked bool
+
+	if s.isOutput {
+		request := proto.GetSinkInputInfo{
+			SinkInputIndex: s.streamIndex,
+		}
+		reply := proto.GetSinkInputInfoReply{}
+
+		if err := s.client.Request(&request, &reply); err != nil {
+			s.logger.Debugw("Failed to get session state", "error", err)
+			return false
+		}
+
+		corked = reply.Corked
+	} else {
+		request := proto.GetSourceOutputInfo{
+			SourceOutpuIndex: s.streamIndex,
+		}
+		reply := proto.GetSourceOutputInfoReply{}
+
+		if err := s.client.Request(&request, &reply); err != nil {
+			s.logger.Debugw("Failed to get session state", "error", err)
+			return false
+		}
+
+		corked = reply.Corked
+	}
+
+	return !corked
+}
+
+func (s *paSession) Playback() bool {
+	return s.isOutput
+}
+
+func (s *paSession) OutputDevice() (string, error) {
+	if !s.isOutput {
+		return "", errNotPlaybackSession
+	}
+
+	request := proto.GetSinkInputInfo{
+		SinkInputIndex: s.streamIndex,
+	}
+	reply := proto.GetSinkInputInfoReply{}
+
+	if err := s.client.Request(&request, &reply); err != nil {
+		s.logger.Warnw("Failed to get session info", "error", err)
+		return "", fmt.Errorf("get sink input info: %w", err)
+	}
+
+	sinkRequest := proto.GetSinkInfo{
+		SinkIndex: reply.SinkIndex,
+	}
+	sinkReply := proto.GetSinkInfoReply{}
+
+	if err := s.client.Request(&sinkRequest, &sinkReply); err != nil {
+		s.logger.Warnw("Failed to get session's sink info", "error", err, "sinkIndex", reply.SinkIndex)
+		return "", fmt.Errorf("get sink info: %w", err)
+	}
+
+	return sinkReply.SinkName, nil
+}
+
+func (s *paSession) MoveToOutputDevice(deviceName string) error {
+	if !s.isOutput {
+		return errNotPlaybackSession
+	}
+
+	request := proto.MoveSinkInput{
+		SinkInputIndex: s.streamIndex,
+		DeviceIndex:    proto.Undefined,
+		DeviceName:     deviceName,
+	}
+
+	if err := s.client.Request(&request, nil); err != nil {
+		s.logger.Warnw("Failed to move session", "error", err, "device", deviceName)
+		return fmt.Errorf("move sink input: %w", err)
+	}
+
+	s.logger.Debugw("Moved session to another output device", "device", deviceName)
+
+	return nil
+}
+
 func (s *paSession) Release() {
 	s.logger.Debug("Releasing audio session")
 }
@@ -134,8 +384,81 @@ func (s *paSession) String() string {
 }
 
 func (s *masterSession) GetVolume() float32 {
-	var level float32
+	_, volumes, err := s.channels()
+	if err != nil {
+		s.logger.Warnw("Failed to get session volume", "error", err)
+		return 0
+	}
+
+	return parseChannelVolumes(volumes)
+}
+
+func (s *masterSession) SetVolume(v float32) error {
+	_, currentVolumes, err := s.channels()
+	if err != nil {
+		s.logger.Warnw("Failed to get session channel volumes, resetting its balance", "error", err)
+	}
+
+	v = clampVolume(v)
+	volumes := s.scaleChannelVolumes(currentVolumes, s.streamChannels, v)
+
+	if err := s.setChannelVolumes(volumes); err != nil {
+		s.logger.Warnw("Failed to set session volume",
+			"error", err,
+			"volume", v)
+
+		return fmt.Errorf("adjust session volume: %w", err)
+	}
+
+	s.logger.Debugw("Adjusting session volume", "to", fmt.Sprintf("%.2f", v))
+
+	return nil
+}
+
+func (s *masterSession) GetBalance() (float32, error) {
+	if !s.isOutput {
+		return 0, errBalanceUnsupported
+	}
+
+	channelMap, volumes, err := s.channels()
+	if err != nil {
+		return 0, fmt.Errorf("get session channel volumes: %w", err)
+	}
+
+	return parseChannelBalance(channelMap, volumes)
+}
+
+func (s *masterSession) SetBalance(b float32) error {
+	if !s.isOutput {
+		return errBalanceUnsupported
+	}
+
+	channelMap, currentVolumes, err := s.channels()
+	if err != nil {
+		s.logger.Warnw("Failed to get session channel volumes", "error", err)
+		return fmt.Errorf("get session channel volumes: %w", err)
+	}
+
+	volumes, err := s.balanceChannelVolumes(channelMap, currentVolumes, b)
+	if err != nil {
+		return err
+	}
+
+	if err := s.setChannelVolumes(volumes); err != nil {
+		s.logger.Warnw("Failed to set session balance",
+			"error", err,
+			"balance", b)
+
+		return fmt.Errorf("adjust session balance: %w", err)
+	}
+
+	s.logger.Debugw("Adjusting session balance", "to", fmt.Sprintf("%.2f", b))
 
+	return nil
+}
+
+// channels returns the device's channel map along with each channel's volume
+func (s *masterSession) channels() (proto.ChannelMap, proto.ChannelVolumes, error) {
 	if s.isOutput {
 		request := proto.GetSinkInfo{
 			SinkIndex: s.streamIndex,
@@ -143,33 +466,27 @@ func (s *masterSession) GetVolume() float32 {
 		reply := proto.GetSinkInfoReply{}
 
 		if err := s.client.Request(&request, &reply); err != nil {
-			s.logger.Warnw("Failed to get session volume", "error", err)
-			return 0
+			return nil, nil, fmt.Errorf("get sink info: %w", err)
 		}
 
-		level = parseChannelVolumes(reply.ChannelVolumes)
-	} else {
-		request := proto.GetSourceInfo{
-			SourceIndex: s.streamIndex,
-		}
-		reply := proto.GetSourceInfoReply{}
+		return reply.ChannelMap, reply.ChannelVolumes, nil
+	}
 
-		if err := s.client.Request(&request, &reply); err != nil {
-			s.logger.Warnw("Failed to get session volume", "error", err)
-			return 0
-		}
+	request := proto.GetSourceInfo{
+		SourceIndex: s.streamIndex,
+	}
+	reply := proto.GetSourceInfoReply{}
 
-		level = parseChannelVolumes(reply.ChannelVolumes)
+	if err := s.client.Request(&request, &reply); err != nil {
+		return nil, nil, fmt.Errorf("get source info: %w", err)
 	}
 
-	return level
+	return reply.ChannelMap, reply.ChannelVolumes, nil
 }
 
-func (s *masterSession) SetVolume(v float32) error {
+func (s *masterSession) setChannelVolumes(volumes proto.ChannelVolumes) error {
 	var request proto.RequestArgs
 
-	volumes := createChannelVolumes(s.streamChannels, v)
-
 	if s.isOutput {
 		request = &proto.SetSinkVolume{
 			SinkIndex:      s.streamIndex,
@@ -182,15 +499,61 @@ func (s *masterSession) SetVolume(v float32) error {
 		}
 	}
 
+	return s.client.Request(request, nil)
+}
+
+func (s *masterSession) GetMute() bool {
+	if s.isOutput {
+		request := proto.GetSinkInfo{
+			SinkIndex: s.streamIndex,
+		}
+		reply := proto.GetSinkInfoReply{}
+
+		if err := s.client.Request(&request, &reply); err != nil {
+			s.logger.Warnw("Failed to get session mute state", "error", err)
+			return false
+		}
+
+		return reply.Mute
+	}
+
+	request := proto.GetSourceInfo{
+		SourceIndex: s.streamIndex,
+	}
+	reply := proto.GetSourceInfoReply{}
+
+	if err := s.client.Request(&request, &reply); err != nil {
+		s.logger.Warnw("Failed to get session mute state", "error", err)
+		return false
+	}
+
+	return reply.Mute
+}
+
+func (s *masterSession) SetMute(m bool) error {
+	var request proto.RequestArgs
+
+	if s.isOutput {
+		request = &proto.SetSinkMute{
+			SinkIndex: s.streamIndex,
+			Mute:      m,
+		}
+	} else {
+		request = &proto.SetSourceMute{
+			SourceIndex: s.streamIndex,
+			Mute:        m,
+		}
+	}
+
 	if err := s.client.Request(request, nil); err != nil {
-		s.logger.Warnw("Failed to set session volume",
+		s.logger.Warnw("Failed to set session mute state",
 			"error", err,
-			"volume", v)
+			"mute", m)
 
-		return fmt.Errorf("adjust session volume: %w", err)
+		return fmt.Errorf("adjust session mute state: %w", err)
 	}
 
-	s.logger.Debugw("Adjusting session volume", "to", fmt.Sprintf("%.2f", v))
+	s.logger.Debugw("Adjusting session mute state", "to", m)
 
 	return nil
 }
@@ -203,6 +566,159 @@ func (s *masterSession) String() string {
 	return fmt.Sprintf(sessionStringFormat, s.humanReadableDesc, s.GetVolume())
 }
 
+// scaleChannelVolumes sets the loudest channel to the given volume, and the other channels relative to it.
+// this keeps whatever balance the user set up (through deej or the OS) instead of flattening it
+func (b *channelBalance) scaleChannelVolumes(currentVolumes proto.ChannelVolumes, channels byte, volume float32) proto.ChannelVolumes {
+	reference := currentVolumes
+
+	if len(reference) == int(channels) && maxChannelVolume(reference) > 0 {
+		b.lastChannelVolumes = append(proto.ChannelVolumes{}, reference...)
+	} else {
+		reference = b.lastChannelVolumes
+	}
+
+	peak := maxChannelVolume(reference)
+
+	// nothing to go by (i.e. the stream started out silent), so just treat all channels the same
+	if len(reference) != int(channels) || peak == 0 {
+		return createChannelVolumes(channels, volume)
+	}
+
+	volumes := make(proto.ChannelVolumes, channels)
+
+	for i := range volumes {
+		volumes[i] = uint32(float64(reference[i]) / float64(peak) * float64(volume) * maxVolume)
+	}
+
+	return volumes
+}
+
+// balanceChannelVolumes pans the stream: 0 is all the way to the left, 0.5 is centered and 1 is all the way
+// to the right. the side being panned towards stays at the stream's current volume, the other side is lowered
+func (b *channelBalance) balanceChannelVolumes(
+	channelMap proto.ChannelMap,
+	currentVolumes proto.ChannelVolumes,
+	balance float32,
+) (proto.ChannelVolumes, error) {
+
+	if len(channelMap) != len(currentVolumes) {
+		return nil, errors.New("channel map doesn't match channel volumes")
+	}
+
+	leftGain, rightGain := 1.0, 1.0
+
+	if balance > 0.5 {
+		leftGain = float64(1-balance) * 2
+	} else {
+		rightGain = float64(balance) * 2
+	}
+
+	peak := float64(maxChannelVolume(currentVolumes))
+	volumes := make(proto.ChannelVolumes, len(currentVolumes))
+	hasSides := false
+
+	for i, position := range channelMap {
+		gain := 1.0
+
+		switch channelSide(position) {
+		case channelSideLeft:
+			gain = leftGain
+			hasSides = true
+
+		case channelSideRight:
+			gain = rightGain
+			hasSides = true
+		}
+
+		volumes[i] = uint32(peak * gain)
+	}
+
+	if !hasSides {
+		return nil, fmt.Errorf("%w: no left or right channels", errBalanceUnsupported)
+	}
+
+	if peak > 0 {
+		b.lastChannelVolumes = append(proto.ChannelVolumes{}, volumes...)
+	}
+
+	return volumes, nil
+}
+
+// parseChannelBalance works out the balance balanceChannelVolumes would've set to end up with the given volumes,
+// going by the loudest channel on each side
+func parseChannelBalance(channelMap proto.ChannelMap, volumes proto.ChannelVolumes) (float32, error) {
+	if len(channelMap) != len(volumes) {
+		return 0, errors.New("channel map doesn't match channel volumes")
+	}
+
+	var left, right uint32
+	hasSides := false
+
+	for i, position := range channelMap {
+		switch channelSide(position) {
+		case channelSideLeft:
+			hasSides = true
+			if volumes[i] > left {
+				left = volumes[i]
+			}
+
+		case channelSideRight:
+			hasSides = true
+			if volumes[i] > right {
+				right = volumes[i]
+			}
+		}
+	}
+
+	if !hasSides {
+		return 0, fmt.Errorf("%w: no left or right channels", errBalanceUnsupported)
+	}
+
+	// a silent stream sounds the same wherever it's panned
+	switch {
+	case left == right:
+		return 0.5, nil
+	case left > right:
+		return float32(right) / float32(left) / 2, nil
+	default:
+		return 1 - float32(left)/float32(right)/2, nil
+	}
+}
+
+const (
+	channelSideNone = iota
+	channelSideLeft
+	channelSideRight
+)
+
+// channelSide returns which side a channel is on, if any (center and LFE channels aren't on either)
+func channelSide(position byte) int {
+	switch position {
+	case proto.ChannelFrontLeft, proto.ChannelRearLeft, proto.ChannelLeftCenter,
+		proto.ChannelLeftSide, proto.ChannelTopFrontLeft, proto.ChannelTopRearLeft:
+		return channelSideLeft
+
+	case proto.ChannelFrontRight, proto.ChannelRearRight, proto.ChannelRightCenter,
+		proto.ChannelRightSide, proto.ChannelTopFrontRight, proto.ChannelTopRearRight:
+		return channelSideRight
+	}
+
+	return channelSideNone
+}
+
+// clampVolume caps boosted volumes, everything up to maxBoostedVolume (including values over 1.0) goes through
+func clampVolume(v float32) float32 {
+	if limit := float32(maxBoostedVolume) / maxVolume; v > limit {
+		return limit
+	}
+
+	if v < 0 {
+		return 0
+	}
+
+	return v
+}
+
 func createChannelVolumes(channels byte, volume float32) []uint32 {
 	volumes := make([]uint32, channels)
 
@@ -213,12 +729,20 @@ func createChannelVolumes(channels byte, volume float32) []uint32 {
 	return volumes
 }
 
+// parseChannelVolumes returns the volume of the loudest channel, which is what the OS shows as the overall volume.
+// an average would make a panned stream look quieter than it is (and jump once the slider moves)
 func parseChannelVolumes(volumes []uint32) float32 {
-	var level uint32
+	return float32(maxChannelVolume(volumes)) / float32(maxVolume)
+}
+
+func maxChannelVolume(volumes []uint32) uint32 {
+	var peak uint32
 
 	for _, volume := range volumes {
-		level += volume
+		if volume > peak {
+			peak = volume
+		}
 	}
 
-	return float32(level) / float32(len(volumes)) / float32(maxVolume)
+	return peak
 }
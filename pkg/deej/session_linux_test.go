@@ -0,0 +1,51 @@
+package deej
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/jfreymuth/pulse/proto"
+)
+
+func TestParseChannelBalance(t *testing.T) {
+	stereo := proto.ChannelMap{proto.ChannelFrontLeft, proto.ChannelFrontRight}
+	surround := proto.ChannelMap{
+		proto.ChannelFrontLeft, proto.ChannelFrontRight, proto.ChannelFrontCenter,
+		proto.ChannelLFE, proto.ChannelRearLeft, proto.ChannelRearRight,
+	}
+
+	// whatever balanceChannelVolumes sets should read back the same
+	for _, channelMap := range []proto.ChannelMap{stereo, surround} {
+		for _, balance := range []float32{0, 0.2, 0.5, 0.65, 1} {
+			current := make(proto.ChannelVolumes, len(channelMap))
+			for i := range current {
+				current[i] = maxVolume / 2
+			}
+
+			volumes, err := (&channelBalance{}).balanceChannelVolumes(channelMap, current, balance)
+			if err != nil {
+				t.Fatalf("balance %.2f: %v", balance, err)
+			}
+
+			parsed, err := parseChannelBalance(channelMap, volumes)
+			if err != nil {
+				t.Fatalf("parse balance %.2f: %v", balance, err)
+			}
+
+			if math.Abs(float64(parsed-balance)) > 0.001 {
+				t.Errorf("%d channels: expected balance %.2f, got %.3f", len(channelMap), balance, parsed)
+			}
+		}
+	}
+
+	// a silent stream reads as centered
+	if balance, err := parseChannelBalance(stereo, proto.ChannelVolumes{0, 0}); err != nil || balance != 0.5 {
+		t.Errorf("expected a silent stream to be centered, got %.2f (error: %v)", balance, err)
+	}
+
+	mono := proto.ChannelMap{proto.ChannelMono}
+	if _, err := parseChannelBalance(mono, proto.ChannelVolumes{maxVolume}); !errors.Is(err, errBalanceUnsupported) {
+		t.Errorf("expected a mono stream to have no balance, got error %v", err)
+	}
+}
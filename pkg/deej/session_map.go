@@ -1,6 +1,7 @@
 package deej
 
 import (
+	"errors"
 	"fmt"
 	"regexp"
 	"strings"
@@ -19,10 +20,38 @@ type sessionMap struct {
 	m    map[string][]Session
 	lock sync.Locker
 
+	// held by whatever uses the sessions (slider moves, ducking, actions, refreshes), so a refresh can't
+	// release them from under anyone. everything below that isn't guarded by a lock of its own is guarded by it
+	useLock sync.Mutex
+
 	sessionFinder SessionFinder
 
 	lastSessionRefresh time.Time
 	unmappedSessions   []Session
+
+	// levels from the last restored scene, waiting for their sessions to show up
+	pendingScene     map[string]sceneLevel
+	pendingSceneLock sync.Mutex
+
+	// ducking state: each rule's current gain (by rule id), the keys it's holding down and the levels sliders last set
+	duckingGains map[string]float32
+	duckedKeys   map[string]*duckedKey
+	sliderLevels map[string]float32
+	duckingLock  sync.Mutex
+
+	// volumes to start apps at, and the session keys seen in the last refresh (to tell which sessions are new)
+	rememberedVolumes     rememberedVolumes
+	rememberedVolumesLock sync.Mutex
+	knownKeys             map[string]bool
+
+	// the volume each session key had when deej first saw it, to restore on exit
+	originalVolumes     map[string]float32
+	originalVolumesLock sync.Mutex
+
+	// base levels for relative sliders, captured when they first touch a session key and dropped once they don't apply
+	relativeBases map[string]float32
+
+	stopChannel chan bool
 }
 
 const (
@@ -30,6 +59,9 @@ const (
 	systemSessionName = "system" // system sounds volume
 	inputSessionName  = "mic"    // microphone input level
 
+	// prefixes the keys of per-application recording streams (Linux-only), e.g. "input:discord"
+	inputSessionPrefix = "input:"
+
 	// some targets need to be transformed before their correct audio sessions can be accessed.
 	// this prefix identifies those targets to ensure they don't contradict with another similarly-named process
 	specialTargetTransformPrefix = "deej."
@@ -40,6 +72,9 @@ const (
 	// targets all currently unmapped sessions (experimental)
 	specialTargetAllUnmapped = "unmapped"
 
+	// makes a slider pan its target left and right instead of changing its volume, e.g. "balance:master"
+	balanceTargetPrefix = "balance:"
+
 	// this threshold constant assumes that re-acquiring all sessions is a kind of expensive operation,
 	// and needs to be limited in some manner. this value was previously user-configurable through a config
 	// key "process_refresh_frequency", but exposing this type of implementation detail seems wrong now
@@ -66,6 +101,13 @@ func newSessionMap(deej *Deej, logger *zap.SugaredLogger, sessionFinder SessionF
 		m:             make(map[string][]Session),
 		lock:          &sync.Mutex{},
 		sessionFinder: sessionFinder,
+		duckingGains:  map[string]float32{},
+		duckedKeys:    map[string]*duckedKey{},
+		sliderLevels:  map[string]float32{},
+
+		originalVolumes: map[string]float32{},
+		relativeBases:   map[string]float32{},
+		stopChannel:     make(chan bool),
 	}
 
 	logger.Debug("Created session map instance")
@@ -74,6 +116,11 @@ func newSessionMap(deej *Deej, logger *zap.SugaredLogger, sessionFinder SessionF
 }
 
 func (m *sessionMap) initialize() error {
+	m.rememberedVolumes.levels = map[string]float32{}
+	for key, volume := range m.deej.config.RememberedVolumes {
+		m.rememberedVolumes.levels[key] = volume
+	}
+
 	if err := m.getAndAddSessions(); err != nil {
 		m.logger.Warnw("Failed to get all sessions during session map initialization", "error", err)
 		return fmt.Errorf("get all sessions during init: %w", err)
@@ -81,11 +128,30 @@ func (m *sessionMap) initialize() error {
 
 	m.setupOnConfigReload()
 	m.setupOnSliderMove()
+	m.setupDucking()
 
 	return nil
 }
 
 func (m *sessionMap) release() error {
+
+	// stop ducking first, so it doesn't touch any volumes from here on
+	close(m.stopChannel)
+
+	m.useLock.Lock()
+	defer m.useLock.Unlock()
+
+	// ducked sessions would otherwise stay down for good once deej is gone
+	m.restoreDuckedKeys()
+
+	// pick up any last volume changes before letting go of the sessions
+	m.snapshotRememberedVolumes()
+	m.saveRememberedVolumes()
+
+	if m.deej.config.RestoreOnExit {
+		m.restoreOriginalVolumes()
+	}
+
 	if err := m.sessionFinder.Release(); err != nil {
 		m.logger.Warnw("Failed to release session finder during session map release", "error", err)
 		return fmt.Errorf("release session finder during release: %w", err)
@@ -95,7 +161,7 @@ func (m *sessionMap) release() error {
 }
 
 // assumes the session map is clean!
-// only call on a new session map or as part of refreshSessions which calls reset
+// only call on a new session map or as part of refreshSessionsLocked which calls reset
 func (m *sessionMap) getAndAddSessions() error {
 
 	// mark that we're refreshing before anything else
@@ -117,6 +183,17 @@ func (m *sessionMap) getAndAddSessions() error {
 		}
 	}
 
+	// note where sessions are at before deej changes anything, in case we need to put them back on exit
+	m.recordOriginalVolumes(sessions)
+
+	// apps that just showed up start out at their remembered volume
+	m.applyRememberedVolumes(sessions)
+
+	// sessions that weren't around when a scene was restored might be now
+	m.pendingSceneLock.Lock()
+	m.applyPendingScene()
+	m.pendingSceneLock.Unlock()
+
 	m.logger.Infow("Got all audio sessions successfully", "sessionMap", m)
 
 	return nil
@@ -130,7 +207,13 @@ func (m *sessionMap) setupOnConfigReload() {
 			select {
 			case <-configReloadedChannel:
 				m.logger.Info("Detected config reload, attempting to re-acquire all audio sessions")
-				m.refreshSessions(false)
+
+				m.useLock.Lock()
+				m.refreshSessionsLocked(false)
+
+				// sliders might have stopped being relative, which the refresh itself could've skipped over
+				m.pruneRelativeBases()
+				m.useLock.Unlock()
 			}
 		}
 	}()
@@ -138,25 +221,54 @@ func (m *sessionMap) setupOnConfigReload() {
 
 func (m *sessionMap) setupOnSliderMove() {
 	sliderEventsChannel := m.deej.serial.SubscribeToSliderMoveEvents()
+	sliderSyncChannel := m.deej.serial.SubscribeToSliderSyncs()
 
 	go func() {
+		initialSceneRestored := false
+
 		for {
 			select {
 			case event := <-sliderEventsChannel:
+				m.useLock.Lock()
 				m.handleSliderMoveEvent(event)
+				m.useLock.Unlock()
+
+			// the first sync sets every slider-mapped session, so restoring the initial scene any earlier would be undone
+			case <-sliderSyncChannel:
+				if initialSceneRestored || m.deej.initialScene == "" {
+					continue
+				}
+
+				initialSceneRestored = true
+
+				if err := m.restoreScene(m.deej.initialScene); err != nil {
+					m.logger.Warnw("Failed to restore initial scene", "scene", m.deej.initialScene, "error", err)
+				}
 			}
 		}
 	}()
 }
 
-// performance: explain why force == true at every such use to avoid unintended forced refresh spams
+// refreshSessions re-acquires all sessions once nothing else is using them
 func (m *sessionMap) refreshSessions(force bool) {
+	m.useLock.Lock()
+	defer m.useLock.Unlock()
+
+	m.refreshSessionsLocked(force)
+}
+
+// performance: explain why force == true at every such use to avoid unintended forced refresh spams.
+// assumes useLock is held
+func (m *sessionMap) refreshSessionsLocked(force bool) {
 
 	// make sure enough time passed since the last refresh, unless force is true in which case always clear
 	if !force && m.lastSessionRefresh.Add(minTimeBetweenSessionRefreshes).After(time.Now()) {
 		return
 	}
 
+	// remember where the current sessions are at before letting go of them
+	m.snapshotRememberedVolumes()
+
 	// clear and release sessions first
 	m.clear()
 
@@ -165,10 +277,52 @@ func (m *sessionMap) refreshSessions(force bool) {
 	} else {
 		m.logger.Debug("Re-acquired sessions successfully")
 	}
+
+	// refreshes are infrequent enough to persist remembered volumes along with them
+	m.saveRememberedVolumes()
+
+	m.pruneRelativeBases()
+}
+
+// records the volume of sessions with keys deej hasn't seen before
+func (m *sessionMap) recordOriginalVolumes(sessions []Session) {
+	if !m.deej.config.RestoreOnExit {
+		return
+	}
+
+	m.originalVolumesLock.Lock()
+	defer m.originalVolumesLock.Unlock()
+
+	for _, session := range sessions {
+		if _, ok := m.originalVolumes[session.Key()]; !ok {
+			m.originalVolumes[session.Key()] = session.GetVolume()
+		}
+	}
+}
+
+// sets every current session back to the volume it had when deej first saw it
+func (m *sessionMap) restoreOriginalVolumes() {
+	m.originalVolumesLock.Lock()
+	defer m.originalVolumesLock.Unlock()
+
+	m.logger.Infow("Restoring original session volumes", "sessions", len(m.originalVolumes))
+
+	for key, volume := range m.originalVolumes {
+		sessions, ok := m.get(key)
+		if !ok {
+			continue
+		}
+
+		for _, session := range sessions {
+			if err := session.SetVolume(volume); err != nil {
+				m.logger.Warnw("Failed to restore original session volume", "session", session, "error", err)
+			}
+		}
+	}
 }
 
 // returns true if a session is not currently mapped to any slider, false otherwise
-// special sessions (master, system, mic) and device-specific sessions always count as mapped,
+// special sessions (master, system, mic), recording streams and device-specific sessions always count as mapped,
 // even when absent from the config. this makes sense for every current feature that uses "unmapped sessions"
 func (m *sessionMap) sessionMapped(session Session) bool {
 
@@ -182,28 +336,68 @@ func (m *sessionMap) sessionMapped(session Session) bool {
 		return true
 	}
 
+	// count per-application recording streams as mapped, "everything else" shouldn't touch capture levels
+	if strings.HasPrefix(session.Key(), inputSessionPrefix) {
+		return true
+	}
+
+	// count ignored sessions as mapped, so catch-all targets never grab them
+	if m.sessionIgnored(session) {
+		return true
+	}
+
 	matchFound := false
 
-	// look through the actual mappings
+	// look through the actual mappings. balance targets count too: panning a session means something has it
 	m.deej.config.SliderMapping.iterate(func(sliderIdx int, targets []string) {
-		for _, target := range targets {
+		if m.anyTargetMatchesSession(withoutBalancePrefixes(targets), session) {
+			matchFound = true
+		}
+	})
 
-			// ignore special transforms
-			if m.targetHasSpecialTransform(target) {
-				continue
-			}
+	if target := m.deej.config.EncoderBalance; target != "" && m.targetMatchesSession(target, session) {
+		matchFound = true
+	}
 
-			// safe to assume this has a single element because we made sure there's no special transform
-			target = m.resolveTarget(target)[0]
+	return matchFound
+}
 
-			if target == session.Key() {
-				matchFound = true
-				return
-			}
+// returns true if the session matches any of the globally ignored targets
+func (m *sessionMap) sessionIgnored(session Session) bool {
+	return m.anyTargetMatchesSession(m.deej.config.IgnoredTargets, session)
+}
+
+func (m *sessionMap) anyTargetMatchesSession(targets []string, session Session) bool {
+	for _, target := range targets {
+		if m.targetMatchesSession(target, session) {
+			return true
 		}
-	})
+	}
 
-	return matchFound
+	return false
+}
+
+// returns true if the given target refers to the given session. special transforms never match, as what they
+// refer to changes all the time (and counting "deej.unmapped" sessions as mapped would defeat its purpose)
+func (m *sessionMap) targetMatchesSession(target string, session Session) bool {
+
+	// property targets match the session itself rather than its key
+	if matcher := m.propertyMatcher(target); matcher != nil {
+		return matcher.matches(session)
+	}
+
+	// ignore special transforms
+	if m.targetHasSpecialTransform(strings.ToLower(target)) {
+		return false
+	}
+
+	// pattern targets match the session's key without expanding them first
+	if pattern := m.keyPattern(target); pattern != nil {
+		return pattern.matches(session.Key())
+	}
+
+	// safe to assume this has a single element because we made sure there's no special transform or pattern
+	return m.resolveTarget(target)[0] == session.Key()
 }
 
 func (m *sessionMap) handleSliderMoveEvent(event SliderMoveEvent) {
@@ -211,7 +405,7 @@ func (m *sessionMap) handleSliderMoveEvent(event SliderMoveEvent) {
 	// first of all, ensure our session map isn't moldy
 	if m.lastSessionRefresh.Add(maxTimeBetweenSessionRefreshes).Before(time.Now()) {
 		m.logger.Debug("Stale session map detected on slider move, refreshing")
-		m.refreshSessions(true)
+		m.refreshSessionsLocked(true)
 	}
 
 	// get the targets mapped to this slider from the config
@@ -222,36 +416,77 @@ func (m *sessionMap) handleSliderMoveEvent(event SliderMoveEvent) {
 		return
 	}
 
+	// sessions matching any of the slider's exclusions are skipped, no matter which target they came from
+	settings, ok := m.deej.config.SliderMapping.getSettings(event.SliderID)
+	if !ok {
+		settings = &sliderSettings{}
+	}
+
+	exclusions := settings.exclude
+
 	targetFound := false
 	adjustmentFailed := false
 
 	// for each possible target for this slider...
 	for _, target := range targets {
 
-		// resolve the target name by cleaning it up and applying any special transformations.
-		// depending on the transformation applied, this can result in more than one target name
-		resolvedTargets := m.resolveTarget(target)
+		// crossfading sliders set each side to its own level, depending on where the slider is,
+		// and leave alone anything that isn't on either side
+		value := event.PercentValue
+		if settings.crossfade != nil {
+			level, ok := settings.crossfade.level(target, event.PercentValue)
+			if !ok {
+				continue
+			}
+
+			value = level
+		}
 
-		// for each resolved target...
-		for _, resolvedTarget := range resolvedTargets {
+		// balance targets refer to their sessions the same way as any other target, minus the prefix
+		balance := strings.HasPrefix(strings.ToLower(target), balanceTargetPrefix)
+		if balance {
+			target = target[len(balanceTargetPrefix):]
+		}
 
-			// check the map for matching sessions
-			sessions, ok := m.get(resolvedTarget)
+		// resolve the target into its matching sessions, either by property or by (possibly transformed) name
+		sessions := funk.Filter(m.sessionsForTarget(target), func(session Session) bool {
+			return !m.anyTargetMatchesSession(exclusions, session)
+		}).([]Session)
+
+		// no sessions matching this target - move on
+		if len(sessions) == 0 {
+			continue
+		}
+
+		targetFound = true
+
+		// iterate all matching sessions and adjust the volume (or balance) of each one
+		for _, session := range sessions {
+			if balance {
+				if err := m.setSessionBalance(session, value); err != nil {
+					m.logger.Warnw("Failed to set target session balance", "error", err)
+					adjustmentFailed = true
+				}
 
-			// no sessions matching this target - move on
-			if !ok {
 				continue
 			}
 
-			targetFound = true
+			// the slider's range is scaled to the target's max volume, which may go past 100%.
+			// in relative mode, it's scaled to the session's own base level instead
+			volume := value * m.maxVolume(target, session)
+			if settings.relative {
+				volume = value * m.relativeBase(settings, session)
+			}
+
+			m.rememberVolume(session, volume)
 
-			// iterate all matching sessions and adjust the volume of each one
-			for _, session := range sessions {
-				if session.GetVolume() != event.PercentValue {
-					if err := session.SetVolume(event.PercentValue); err != nil {
-						m.logger.Warnw("Failed to set target session volume", "error", err)
-						adjustmentFailed = true
-					}
+			// ducked sessions stay down, but remember where the slider wants them for when ducking ends
+			volume = m.duckVolume(session, volume)
+
+			if session.GetVolume() != volume {
+				if err := session.SetVolume(volume); err != nil {
+					m.logger.Warnw("Failed to set target session volume", "error", err)
+					adjustmentFailed = true
 				}
 			}
 		}
@@ -261,14 +496,247 @@ func (m *sessionMap) handleSliderMoveEvent(event SliderMoveEvent) {
 	// processes could've opened since the last time this slider moved.
 	// if they haven't, the cooldown will take care to not spam it up
 	if !targetFound {
-		m.refreshSessions(false)
+		m.refreshSessionsLocked(false)
 	} else if adjustmentFailed {
 
 		// performance: the reason that forcing a refresh here is okay is that we'll only get here
 		// when a session's SetVolume call errored, such as in the case of a stale master session
 		// (or another, more catastrophic failure happens)
-		m.refreshSessions(true)
+		m.refreshSessionsLocked(true)
+	}
+}
+
+// returns the level a relative slider at 100% sets for the given session: its configured base level if it has one,
+// otherwise the level it had when the slider first touched it
+func (m *sessionMap) relativeBase(settings *sliderSettings, session Session) float32 {
+	for target, base := range settings.bases {
+		if m.targetMatchesSession(target, session) {
+			return base
+		}
+	}
+
+	base, ok := m.relativeBases[session.Key()]
+	if !ok {
+
+		// a ducked session's current volume is only ducking's doing, so its base is the level it'd be at otherwise
+		m.duckingLock.Lock()
+		if ducked, isDucked := m.duckedKeys[session.Key()]; isDucked {
+			base = ducked.level
+		} else {
+			base = session.GetVolume()
+		}
+		m.duckingLock.Unlock()
+
+		// a session that starts out silent would stay that way no matter where the slider goes
+		if base == 0 {
+			base = 1
+		}
+
+		m.logger.Debugw("Captured relative base level", "session", session, "base", base)
+		m.relativeBases[session.Key()] = base
 	}
+
+	return base
+}
+
+// drops the base levels that no longer apply: those of sessions that went away (they get a fresh one when they're back)
+// and those of sessions no relative slider controls anymore. bases still in use are kept, as recapturing them would
+// pick up the level the slider itself set. assumes useLock is held
+func (m *sessionMap) pruneRelativeBases() {
+	for key := range m.relativeBases {
+		sessions, ok := m.get(key)
+		if !ok || len(sessions) == 0 || !m.onRelativeSlider(sessions[0]) {
+			m.logger.Debugw("Dropping relative base level", "key", key)
+			delete(m.relativeBases, key)
+		}
+	}
+}
+
+// returns true if any relative slider controls the session's volume. special targets can reach any session,
+// depending on what's going on (i.e. which window has focus), so sliders using them count no matter what
+func (m *sessionMap) onRelativeSlider(session Session) bool {
+	sliderTargets := map[int][]string{}
+
+	m.deej.config.SliderMapping.iterate(func(sliderIdx int, targets []string) {
+		sliderTargets[sliderIdx] = targets
+	})
+
+	for sliderIdx, targets := range sliderTargets {
+		settings, ok := m.deej.config.SliderMapping.getSettings(sliderIdx)
+		if !ok || !settings.relative {
+			continue
+		}
+
+		for _, target := range targets {
+			if strings.HasPrefix(strings.ToLower(target), balanceTargetPrefix) {
+				continue
+			}
+
+			if m.targetHasSpecialTransform(strings.ToLower(target)) || m.targetMatchesSession(target, session) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// returns the volume a slider at 100% sets for the given session. a max volume set for the slider's target
+// itself comes first (which is the only way to reach special targets). otherwise, if several other targets
+// match the session, the lowest of their max volumes wins
+func (m *sessionMap) maxVolume(target string, session Session) float32 {
+	limits := m.deej.config.MaxVolume
+
+	if limit, ok := limits[strings.ToLower(target)]; ok {
+		return limit
+	}
+
+	lowest := float32(-1)
+
+	for limitTarget, limit := range limits {
+		if (lowest < 0 || limit < lowest) && m.targetMatchesSession(limitTarget, session) {
+			lowest = limit
+		}
+	}
+
+	if lowest < 0 {
+		return 1.0
+	}
+
+	return lowest
+}
+
+// returns the balance of the first session matching the target that has one to report
+func (m *sessionMap) targetBalance(target string) (float32, bool) {
+	m.useLock.Lock()
+	defer m.useLock.Unlock()
+
+	for _, session := range m.sessionsForTarget(target) {
+		balanced, ok := session.(balancedSession)
+		if !ok {
+			continue
+		}
+
+		if balance, err := balanced.GetBalance(); err == nil {
+			return balance, true
+		}
+	}
+
+	return 0, false
+}
+
+// pans every session matching the target, i.e. as the encoder turns. sliders pan theirs as part of handling moves
+func (m *sessionMap) setTargetBalance(target string, balance float32) error {
+	m.useLock.Lock()
+	defer m.useLock.Unlock()
+
+	sessions := m.sessionsForTarget(target)
+
+	// the app could've just started, the refresh cooldown keeps this from getting spammy
+	if len(sessions) == 0 {
+		m.refreshSessionsLocked(false)
+		sessions = m.sessionsForTarget(target)
+	}
+
+	failed := false
+
+	for _, session := range sessions {
+		if err := m.setSessionBalance(session, balance); err != nil {
+			m.logger.Warnw("Failed to set target session balance", "session", session, "error", err)
+			failed = true
+		}
+	}
+
+	if failed {
+		return fmt.Errorf("set balance of %s", target)
+	}
+
+	return nil
+}
+
+func (m *sessionMap) setSessionBalance(session Session, balance float32) error {
+	balanced, ok := session.(balancedSession)
+	if !ok {
+		m.logger.Debugw("Session doesn't support balance control, skipping", "session", session)
+		return nil
+	}
+
+	if err := balanced.SetBalance(balance); err != nil && !errors.Is(err, errBalanceUnsupported) {
+		return err
+	}
+
+	return nil
+}
+
+// withoutBalancePrefixes returns the targets with any "balance:" prefix taken off
+func withoutBalancePrefixes(targets []string) []string {
+	stripped := make([]string, len(targets))
+
+	for targetIdx, target := range targets {
+		if strings.HasPrefix(strings.ToLower(target), balanceTargetPrefix) {
+			target = target[len(balanceTargetPrefix):]
+		}
+
+		stripped[targetIdx] = target
+	}
+
+	return stripped
+}
+
+// returns every session the given target currently refers to
+func (m *sessionMap) sessionsForTarget(target string) []Session {
+
+	// property targets need to look at each session, not just at its key
+	if matcher := m.propertyMatcher(target); matcher != nil {
+		return m.getMatching(matcher.matches)
+	}
+
+	// resolve the target name by cleaning it up and applying any special transformations.
+	// depending on the transformation applied, this can result in more than one target name
+	sessions := []Session{}
+
+	for _, resolvedTarget := range m.resolveTarget(target) {
+		if matching, ok := m.get(resolvedTarget); ok {
+			sessions = append(sessions, matching...)
+		}
+	}
+
+	// ignored sessions can only be reached by naming them explicitly, not through special targets or patterns
+	if m.targetHasSpecialTransform(strings.ToLower(target)) || m.keyPattern(target) != nil {
+		sessions = funk.Filter(sessions, func(session Session) bool {
+			return !m.sessionIgnored(session) || session.Key() == strings.ToLower(target)
+		}).([]Session)
+	}
+
+	return sessions
+}
+
+// returns the property matcher for the given target, or nil if it doesn't select sessions by property
+func (m *sessionMap) propertyMatcher(target string) *propertyMatcher {
+	if matcher := m.targetMatcher(target); matcher != nil {
+		return matcher.property
+	}
+
+	return nil
+}
+
+// returns the glob or regex pattern for the given target, or nil if it doesn't select sessions by pattern
+func (m *sessionMap) keyPattern(target string) *keyPattern {
+	if matcher := m.targetMatcher(target); matcher != nil {
+		return matcher.pattern
+	}
+
+	return nil
+}
+
+func (m *sessionMap) targetMatcher(target string) *targetMatcher {
+	matcher, err := compileTarget(target)
+	if err != nil {
+		m.logger.Warnw("Ignoring invalid target", "target", target, "error", err)
+		return nil
+	}
+
+	return matcher
 }
 
 func (m *sessionMap) targetHasSpecialTransform(target string) bool {
@@ -277,6 +745,9 @@ func (m *sessionMap) targetHasSpecialTransform(target string) bool {
 
 func (m *sessionMap) resolveTarget(target string) []string {
 
+	// patterns need the original case (regexes can be case-sensitive in their syntax), keep it around
+	originalTarget := target
+
 	// start by ignoring the case
 	target = strings.ToLower(target)
 
@@ -285,6 +756,11 @@ func (m *sessionMap) resolveTarget(target string) []string {
 		return m.applyTargetTransform(strings.TrimPrefix(target, specialTargetTransformPrefix))
 	}
 
+	// glob and regex targets expand to the keys of every current session they match
+	if pattern := m.keyPattern(originalTarget); pattern != nil {
+		return m.keysMatching(pattern.matches)
+	}
+
 	return []string{target}
 }
 
@@ -345,6 +821,38 @@ func (m *sessionMap) get(key string) ([]Session, bool) {
 	return value, ok
 }
 
+func (m *sessionMap) keysMatching(predicate func(string) bool) []string {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	matching := []string{}
+
+	for key := range m.m {
+		if predicate(key) {
+			matching = append(matching, key)
+		}
+	}
+
+	return matching
+}
+
+func (m *sessionMap) getMatching(predicate func(Session) bool) []Session {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	matching := []Session{}
+
+	for _, sessions := range m.m {
+		for _, session := range sessions {
+			if predicate(session) {
+				matching = append(matching, session)
+			}
+		}
+	}
+
+	return matching
+}
+
 func (m *sessionMap) clear() {
 	m.lock.Lock()
 	defer m.lock.Unlock()
@@ -0,0 +1,191 @@
+package deej
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+// testSession is a Session that only keeps track of its levels
+type testSession struct {
+	key    string
+	volume float32
+	muted  bool
+}
+
+func (s *testSession) GetVolume() float32 {
+	return s.volume
+}
+
+func (s *testSession) SetVolume(v float32) error {
+	s.volume = v
+	return nil
+}
+
+func (s *testSession) GetMute() bool {
+	return s.muted
+}
+
+func (s *testSession) SetMute(m bool) error {
+	s.muted = m
+	return nil
+}
+
+func (s *testSession) Key() string {
+	return s.key
+}
+
+func (s *testSession) Property(name string) (string, bool) {
+	return "", false
+}
+
+func (s *testSession) Release() {}
+
+func (s *testSession) String() string {
+	return fmt.Sprintf(sessionStringFormat, s.key, s.volume)
+}
+
+// testSessionFinder hands out a fixed set of sessions
+type testSessionFinder struct {
+	sessions []Session
+	released bool
+}
+
+func (f *testSessionFinder) GetAllSessions() ([]Session, error) {
+	return f.sessions, nil
+}
+
+func (f *testSessionFinder) Release() error {
+	f.released = true
+	return nil
+}
+
+func newTestSessionMap(t *testing.T, config *CanonicalConfig) *sessionMap {
+	t.Helper()
+
+	m, err := newSessionMap(&Deej{logger: zap.NewNop().Sugar(), config: config}, zap.NewNop().Sugar(), nil)
+	if err != nil {
+		t.Fatalf("create session map: %v", err)
+	}
+
+	return m
+}
+
+func TestSessionMapMaxVolume(t *testing.T) {
+	m := newTestSessionMap(t, &CanonicalConfig{
+		MaxVolume: map[string]float32{
+			"discord.exe": 1.5,
+			"re:^disc":    0.8,
+			"re:cord":     0.6,
+			"spotify.exe": 0.9,
+		},
+	})
+
+	discord := &testSession{key: "discord.exe"}
+	firefox := &testSession{key: "firefox.exe"}
+
+	tests := []struct {
+		target   string
+		session  Session
+		expected float32
+	}{
+		// the slider's own target comes first, even when it isn't the lowest
+		{"discord.exe", discord, 1.5},
+		{"re:cord", discord, 0.6},
+
+		// otherwise the lowest of every matching target wins, whichever order the map gives them in
+		{"deej.current", discord, 0.6},
+
+		{"deej.current", firefox, 1.0},
+	}
+
+	for _, test := range tests {
+
+		// map order changes between runs, so give it a few chances to pick the wrong one
+		for attempt := 0; attempt < 20; attempt++ {
+			if limit := m.maxVolume(test.target, test.session); limit != test.expected {
+				t.Fatalf("target %s, session %s: expected max volume %.2f, got %.2f",
+					test.target, test.session.Key(), test.expected, limit)
+			}
+		}
+	}
+}
+
+func TestSessionMapRelativeBases(t *testing.T) {
+	relativeMapping, err := sliderMapFromConfigs(map[string]interface{}{
+		"0": map[string]interface{}{"targets": []interface{}{"discord.exe", "spotify.exe"}, "mode": "relative"},
+	}, nil)
+	if err != nil {
+		t.Fatalf("parse slider mapping: %v", err)
+	}
+
+	m := newTestSessionMap(t, &CanonicalConfig{SliderMapping: relativeMapping})
+	settings, _ := relativeMapping.getSettings(0)
+
+	discord := &testSession{key: "discord.exe", volume: 0.2}
+	spotify := &testSession{key: "spotify.exe", volume: 0.5}
+	firefox := &testSession{key: "firefox.exe", volume: 0.7}
+
+	for _, session := range []*testSession{discord, spotify, firefox} {
+		m.m[session.key] = []Session{session}
+	}
+
+	// discord is only at 0.2 because it's ducked, its base is the level it'd be at otherwise
+	m.duckedKeys[discord.key] = &duckedKey{level: 0.8, gain: 0.25}
+
+	if base := m.relativeBase(settings, discord); base != 0.8 {
+		t.Errorf("expected discord's base to be its unducked level 0.8, got %.2f", base)
+	}
+
+	if base := m.relativeBase(settings, spotify); base != 0.5 {
+		t.Errorf("expected spotify's base to be its current volume 0.5, got %.2f", base)
+	}
+
+	// once captured, a base stays put no matter where the slider takes the session
+	spotify.volume = 0.1
+	if base := m.relativeBase(settings, spotify); base != 0.5 {
+		t.Errorf("expected spotify's base to stay at 0.5, got %.2f", base)
+	}
+
+	m.relativeBases[firefox.key] = 0.7
+	m.relativeBases["closed.exe"] = 0.3
+
+	m.pruneRelativeBases()
+
+	expected := map[string]float32{discord.key: 0.8, spotify.key: 0.5}
+	if !reflect.DeepEqual(m.relativeBases, expected) {
+		t.Errorf("expected only the bases of sessions on the relative slider to be kept, got %v", m.relativeBases)
+	}
+
+	// the slider stops being relative, as it would after a config reload
+	absoluteMapping, err := sliderMapFromConfigs(map[string]interface{}{
+		"0": []interface{}{"discord.exe", "spotify.exe"},
+	}, nil)
+	if err != nil {
+		t.Fatalf("parse slider mapping: %v", err)
+	}
+
+	m.deej.config.SliderMapping = absoluteMapping
+	m.pruneRelativeBases()
+
+	if len(m.relativeBases) != 0 {
+		t.Errorf("expected every base to be dropped, got %v", m.relativeBases)
+	}
+}
+
+// testBalancedSession is a testSession with a balance
+type testBalancedSession struct {
+	testSession
+	balance float32
+}
+
+func (s *testBalancedSession) GetBalance() (float32, error) {
+	return s.balance, nil
+}
+
+func (s *testBalancedSession) SetBalance(b float32) error {
+	s.balance = b
+	return nil
+}
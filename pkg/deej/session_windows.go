@@ -3,6 +3,7 @@ package deej
 import (
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 
 	ole "github.com/go-ole/go-ole"
@@ -77,6 +78,11 @@ func newWCASession(
 		s.processName = process.Executable()
 		s.name = s.processName
 		s.humanReadableDesc = fmt.Sprintf("%s (pid %d)", s.processName, s.pid)
+
+		s.properties = map[string]string{
+			propertyProcessBinary: s.processName,
+			propertyProcessID:     strconv.FormatUint(uint64(s.pid), 10),
+		}
 	}
 
 	// use a self-identifying session name e.g. deej.sessions.chrome
@@ -119,7 +125,24 @@ func (s *wcaSession) GetVolume() float32 {
 	return level
 }
 
+// clampVolume keeps volumes within the 0.0 - 1.0 range windows accepts
+func clampVolume(v float32) float32 {
+	if v > 1 {
+		return 1
+	}
+
+	if v < 0 {
+		return 0
+	}
+
+	return v
+}
+
 func (s *wcaSession) SetVolume(v float32) error {
+
+	// windows has no notion of boosting a volume past 100%
+	v = clampVolume(v)
+
 	if err := s.volume.SetMasterVolume(v, s.eventCtx); err != nil {
 		s.logger.Warnw("Failed to set session volume", "error", err)
 		return fmt.Errorf("adjust session volume: %w", err)
@@ -143,6 +166,39 @@ func (s *wcaSession) SetVolume(v float32) error {
 	return nil
 }
 
+// Active returns true while the session is playing audio
+func (s *wcaSession) Active() bool {
+	var state uint32
+
+	if err := s.control.GetState(&state); err != nil {
+		s.logger.Debugw("Failed to get session state", "error", err)
+		return false
+	}
+
+	return state == wca.AudioSessionStateActive
+}
+
+func (s *wcaSession) GetMute() bool {
+	var muted bool
+
+	if err := s.volume.GetMute(&muted); err != nil {
+		s.logger.Warnw("Failed to get session mute state", "error", err)
+	}
+
+	return muted
+}
+
+func (s *wcaSession) SetMute(m bool) error {
+	if err := s.volume.SetMute(m, s.eventCtx); err != nil {
+		s.logger.Warnw("Failed to set session mute state", "error", err)
+		return fmt.Errorf("adjust session mute state: %w", err)
+	}
+
+	s.logger.Debugw("Adjusting session mute state", "to", m)
+
+	return nil
+}
+
 func (s *wcaSession) Release() {
 	s.logger.Debug("Releasing audio session")
 
@@ -170,6 +226,8 @@ func (s *masterSession) SetVolume(v float32) error {
 		return errRefreshSessions
 	}
 
+	v = clampVolume(v)
+
 	if err := s.volume.SetMasterVolumeLevelScalar(v, s.eventCtx); err != nil {
 		s.logger.Warnw("Failed to set session volume",
 			"error", err,
@@ -183,6 +241,35 @@ func (s *masterSession) SetVolume(v float32) error {
 	return nil
 }
 
+func (s *masterSession) GetMute() bool {
+	var muted bool
+
+	if err := s.volume.GetMute(&muted); err != nil {
+		s.logger.Warnw("Failed to get session mute state", "error", err)
+	}
+
+	return muted
+}
+
+func (s *masterSession) SetMute(m bool) error {
+	if s.stale {
+		s.logger.Warnw("Session expired because default device has changed, triggering session refresh")
+		return errRefreshSessions
+	}
+
+	if err := s.volume.SetMute(m, s.eventCtx); err != nil {
+		s.logger.Warnw("Failed to set session mute state",
+			"error", err,
+			"mute", m)
+
+		return fmt.Errorf("adjust session mute state: %w", err)
+	}
+
+	s.logger.Debugw("Adjusting session mute state", "to", m)
+
+	return nil
+}
+
 func (s *masterSession) Release() {
 	s.logger.Debug("Releasing audio session")
 
@@ -1,35 +1,75 @@
 package deej
 
 import (
+	"errors"
 	"fmt"
 	"strconv"
+	"strings"
 	"sync"
 
 	"github.com/thoas/go-funk"
 )
 
 type sliderMap struct {
-	m    map[int][]string
-	lock sync.Locker
+	m        map[int][]string
+	settings map[int]*sliderSettings
+	lock     sync.Locker
 }
 
+// sliderSettings holds a slider's optional settings, which can be given by mapping the slider to a map of
+// fields (with its targets under "targets") instead of a target name or list
+type sliderSettings struct {
+	exclude []string // targets this slider should leave alone, even if its own targets match them
+
+	// in relative mode, the slider scales each session's own base level instead of setting one level for all
+	relative bool
+	bases    map[string]float32 // configured base levels by target, sessions without one use their level at first sight
+
+	crossfade *crossfade // set when the slider fades between two targets
+}
+
+const (
+	sliderMappingKeyTargets = "targets"
+	sliderMappingKeyExclude = "exclude"
+	sliderMappingKeyMode    = "mode"
+	sliderMappingKeyBase    = "base"
+
+	sliderMappingKeyCrossfade = "crossfade"
+
+	sliderModeAbsolute = "absolute"
+	sliderModeRelative = "relative"
+)
+
 func newSliderMap() *sliderMap {
 	return &sliderMap{
-		m:    make(map[int][]string),
-		lock: &sync.Mutex{},
+		m:        make(map[int][]string),
+		settings: make(map[int]*sliderSettings),
+		lock:     &sync.Mutex{},
 	}
 }
 
-func sliderMapFromConfigs(userMapping map[string][]string, internalMapping map[string][]string) *sliderMap {
+// sliderMapFromConfigs merges the slider mappings from both configs. invalid settings are reported
+// in the returned error, but don't keep the rest of the mapping from being used
+func sliderMapFromConfigs(userMapping map[string]interface{}, internalMapping map[string][]string) (*sliderMap, error) {
 	resultMap := newSliderMap()
+	var errs []string
 
-	// copy targets from user config, ignoring empty values
-	for sliderIdxString, targets := range userMapping {
+	// copy targets (and settings, if any) from user config, ignoring empty values
+	for sliderIdxString, value := range userMapping {
 		sliderIdx, _ := strconv.Atoi(sliderIdxString)
 
+		targets, settings, err := parseSliderMapping(value)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("slider %d: %v", sliderIdx, err))
+		}
+
 		resultMap.set(sliderIdx, funk.FilterString(targets, func(s string) bool {
 			return s != ""
 		}))
+
+		if settings != nil {
+			resultMap.setSettings(sliderIdx, settings)
+		}
 	}
 
 	// add targets from internal configs, ignoring duplicate or empty values
@@ -49,7 +89,85 @@ func sliderMapFromConfigs(userMapping map[string][]string, internalMapping map[s
 		resultMap.set(sliderIdx, existingTargets)
 	}
 
-	return resultMap
+	if len(errs) > 0 {
+		return resultMap, errors.New(strings.Join(errs, "; "))
+	}
+
+	return resultMap, nil
+}
+
+// parseSliderMapping accepts either a single target, a list of targets or a map of settings for one slider
+func parseSliderMapping(value interface{}) ([]string, *sliderSettings, error) {
+	fields, ok := value.(map[string]interface{})
+	if !ok {
+		return toStringSlice(value), nil, nil
+	}
+
+	settings := &sliderSettings{
+		exclude: toStringSlice(fields[sliderMappingKeyExclude]),
+		bases:   map[string]float32{},
+	}
+
+	targets := toStringSlice(fields[sliderMappingKeyTargets])
+
+	switch mode := strings.ToLower(fmt.Sprint(fields[sliderMappingKeyMode])); mode {
+	case sliderModeRelative:
+		settings.relative = true
+	case sliderModeAbsolute, "<nil>":
+	default:
+		return targets, settings, fmt.Errorf("unknown mode %q", mode)
+	}
+
+	if baseValue, ok := fields[sliderMappingKeyBase]; ok {
+		bases, ok := toStringKeyedMap(baseValue)
+		if !ok {
+			return targets, settings, errors.New("base levels must be a map of targets to levels")
+		}
+
+		for target, level := range bases {
+			parsedLevel, err := strconv.ParseFloat(fmt.Sprint(level), 32)
+			if err != nil || parsedLevel < 0 {
+				return targets, settings, fmt.Errorf("invalid base level %v for %s", level, target)
+			}
+
+			settings.bases[target] = float32(parsedLevel)
+		}
+	}
+
+	// a crossfading slider is mapped to both of its sides
+	if crossfadeValue, ok := fields[sliderMappingKeyCrossfade]; ok {
+		crossfade, err := parseCrossfade(crossfadeValue)
+		if err != nil {
+			return targets, settings, err
+		}
+
+		settings.crossfade = crossfade
+		targets = crossfade.targets()
+	}
+
+	return targets, settings, nil
+}
+
+// toStringSlice converts a config value that can be either a single string or a list into a string slice.
+// unlike viper's own helpers, a single string is kept whole (device names and patterns can contain spaces)
+func toStringSlice(value interface{}) []string {
+	switch typed := value.(type) {
+	case string:
+		return []string{typed}
+	case []string:
+		return typed
+	case []interface{}:
+		result := make([]string, 0, len(typed))
+		for _, item := range typed {
+			if item != nil {
+				result = append(result, fmt.Sprint(item))
+			}
+		}
+
+		return result
+	}
+
+	return []string{}
 }
 
 func (m *sliderMap) iterate(f func(int, []string)) {
@@ -76,6 +194,21 @@ func (m *sliderMap) set(key int, value []string) {
 	m.m[key] = value
 }
 
+func (m *sliderMap) getSettings(key int) (*sliderSettings, bool) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	value, ok := m.settings[key]
+	return value, ok
+}
+
+func (m *sliderMap) setSettings(key int, value *sliderSettings) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.settings[key] = value
+}
+
 func (m *sliderMap) String() string {
 	m.lock.Lock()
 	defer m.lock.Unlock()
@@ -0,0 +1,202 @@
+package deej
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// targetMatcher is the compiled form of a target that does more than name a single session key
+type targetMatcher struct {
+	property *propertyMatcher // set for property targets, i.e. "role:music"
+	pattern  *keyPattern      // set for glob and regex targets, i.e. "steam_app_*"
+}
+
+// propertyMatcher selects sessions by one of their properties rather than by their key,
+// which helps with apps whose binary name is useless (flatpak, browsers, games running under wine)
+type propertyMatcher struct {
+	property string
+
+	value   string         // compared case-insensitively, unless pattern is set
+	pattern *regexp.Regexp // set for "=~" matchers
+}
+
+// keyPattern selects sessions whose key matches a glob ("steam_app_*", "*.exe") or a regex ("re:^java(w)?$")
+type keyPattern struct {
+	glob  string
+	regex *regexp.Regexp
+}
+
+const (
+
+	// arbitrary property matchers look like "prop:media.name=Spotify" or "prop:media.name=~spot(ify)?"
+	propertyMatcherPrefix      = "prop:"
+	propertyMatcherRegexMarker = "~"
+
+	// regex targets are explicitly marked, anything containing these characters otherwise counts as a glob.
+	// plenty of names have brackets in them too (i.e. "Speakers [Realtek]"), so globs still match their own name exactly
+	keyPatternRegexPrefix = "re:"
+	keyPatternGlobChars   = "*?["
+)
+
+// shorthands for the properties people are most likely to match on
+var propertyMatcherShorthands = map[string]string{
+	"name:": propertyApplicationName,
+	"role:": propertyMediaRole,
+	"pid:":  propertyProcessID,
+}
+
+var (
+	targetMatcherCache     = map[string]*targetMatcher{}
+	targetMatcherCacheLock sync.Mutex
+)
+
+// compileTarget returns a matcher for the given target, or nil if the target is a plain session key.
+// matchers are cached, since they're looked up on every slider move
+func compileTarget(target string) (*targetMatcher, error) {
+	targetMatcherCacheLock.Lock()
+	defer targetMatcherCacheLock.Unlock()
+
+	if matcher, ok := targetMatcherCache[target]; ok {
+		return matcher, nil
+	}
+
+	// invalid targets are cached as well (as matching nothing), so the error only surfaces once
+	matcher, err := parseTargetMatcher(target)
+	targetMatcherCache[target] = matcher
+
+	return matcher, err
+}
+
+func parseTargetMatcher(target string) (*targetMatcher, error) {
+	property, err := parsePropertyMatcher(target)
+	if err != nil {
+		return nil, err
+	}
+
+	if property != nil {
+		return &targetMatcher{property: property}, nil
+	}
+
+	pattern, err := parseKeyPattern(target)
+	if err != nil {
+		return nil, err
+	}
+
+	if pattern != nil {
+		return &targetMatcher{pattern: pattern}, nil
+	}
+
+	return nil, nil
+}
+
+func parsePropertyMatcher(target string) (*propertyMatcher, error) {
+	lowerTarget := strings.ToLower(target)
+
+	for prefix, property := range propertyMatcherShorthands {
+		if strings.HasPrefix(lowerTarget, prefix) {
+			return &propertyMatcher{
+				property: property,
+				value:    target[len(prefix):],
+			}, nil
+		}
+	}
+
+	if !strings.HasPrefix(lowerTarget, propertyMatcherPrefix) {
+		return nil, nil
+	}
+
+	// split "media.name=~Spotify" into the property name and the expected value
+	expression := target[len(propertyMatcherPrefix):]
+
+	separatorIdx := strings.Index(expression, "=")
+	if separatorIdx <= 0 {
+		return nil, fmt.Errorf("property target %q must look like prop:<name>=<value>", target)
+	}
+
+	matcher := &propertyMatcher{
+		property: expression[:separatorIdx],
+		value:    expression[separatorIdx+1:],
+	}
+
+	if strings.HasPrefix(matcher.value, propertyMatcherRegexMarker) {
+		pattern, err := regexp.Compile("(?i)" + strings.TrimPrefix(matcher.value, propertyMatcherRegexMarker))
+		if err != nil {
+			return nil, fmt.Errorf("compile property target %q: %w", target, err)
+		}
+
+		matcher.pattern = pattern
+	}
+
+	return matcher, nil
+}
+
+func parseKeyPattern(target string) (*keyPattern, error) {
+
+	// regexes keep their original case, lowercasing them could change their meaning (\S vs \s)
+	if strings.HasPrefix(strings.ToLower(target), keyPatternRegexPrefix) {
+		regex, err := regexp.Compile("(?i)" + target[len(keyPatternRegexPrefix):])
+		if err != nil {
+			return nil, fmt.Errorf("compile regex target %q: %w", target, err)
+		}
+
+		return &keyPattern{regex: regex}, nil
+	}
+
+	if !strings.ContainsAny(target, keyPatternGlobChars) {
+		return nil, nil
+	}
+
+	// session keys are always lowercase, and so should the glob be
+	glob := strings.ToLower(target)
+
+	// not a valid glob (i.e. "Speakers [Realtek"), so it can only be a plain name
+	if _, err := path.Match(glob, ""); err != nil {
+		return nil, nil
+	}
+
+	return &keyPattern{glob: glob}, nil
+}
+
+func (pm *propertyMatcher) matches(session Session) bool {
+	value, ok := session.Property(pm.property)
+	if !ok {
+		return false
+	}
+
+	if pm.pattern != nil {
+		return pm.pattern.MatchString(value)
+	}
+
+	return strings.EqualFold(value, pm.value)
+}
+
+func (pm *propertyMatcher) String() string {
+	if pm.pattern != nil {
+		return fmt.Sprintf("<%s=~%s>", pm.property, pm.pattern)
+	}
+
+	return fmt.Sprintf("<%s=%s>", pm.property, pm.value)
+}
+
+func (kp *keyPattern) matches(key string) bool {
+	if kp.regex != nil {
+		return kp.regex.MatchString(key)
+	}
+
+	// the glob was validated when it was parsed, so no error can come out of this
+	matched, _ := path.Match(kp.glob, key)
+
+	// "Speakers [Realtek]" is a glob for "speakers r", but whoever wrote it most likely meant the name itself
+	return matched || key == kp.glob
+}
+
+func (kp *keyPattern) String() string {
+	if kp.regex != nil {
+		return fmt.Sprintf("<re:%s>", kp.regex)
+	}
+
+	return fmt.Sprintf("<glob:%s>", kp.glob)
+}
@@ -0,0 +1,79 @@
+package deej
+
+import (
+	"testing"
+)
+
+func TestKeyPatternMatches(t *testing.T) {
+	tests := []struct {
+		target     string
+		matches    []string
+		mismatches []string
+	}{
+		{"steam_app_*", []string{"steam_app_123", "steam_app_"}, []string{"steam", "my_steam_app_1"}},
+		{"*.EXE", []string{"discord.exe", "chrome.exe"}, []string{"discord"}},
+		{"java?", []string{"javaw"}, []string{"java", "javaws"}},
+		{"re:^java(w)?$", []string{"java", "javaw"}, []string{"javaws", "openjava"}},
+
+		// regexes are case-insensitive, and keep their own case so escapes keep their meaning
+		{`re:^\S+\.EXE$`, []string{"discord.exe"}, []string{"my app.exe"}},
+
+		// names that only look like globs still match themselves
+		{"Speakers [Realtek]", []string{"speakers [realtek]", "speakers r"}, []string{"speakers realtek"}},
+	}
+
+	for _, test := range tests {
+		pattern, err := parseKeyPattern(test.target)
+		if err != nil {
+			t.Fatalf("parse %q: %v", test.target, err)
+		}
+
+		if pattern == nil {
+			t.Fatalf("expected %q to be a pattern", test.target)
+		}
+
+		for _, key := range test.matches {
+			if !pattern.matches(key) {
+				t.Errorf("expected %s to match %q", pattern, key)
+			}
+		}
+
+		for _, key := range test.mismatches {
+			if pattern.matches(key) {
+				t.Errorf("expected %s not to match %q", pattern, key)
+			}
+		}
+	}
+}
+
+func TestParseKeyPattern(t *testing.T) {
+
+	// plain names, and names that aren't valid globs, aren't patterns at all
+	for _, target := range []string{"discord.exe", "master", "Speakers [Realtek"} {
+		pattern, err := parseKeyPattern(target)
+		if err != nil || pattern != nil {
+			t.Errorf("expected %q to be a plain target, got %v (error: %v)", target, pattern, err)
+		}
+	}
+
+	if _, err := parseKeyPattern("re:java(w"); err == nil {
+		t.Error("expected an invalid regex to be reported")
+	}
+}
+
+func TestSessionMapTargetMatchesSession(t *testing.T) {
+	m := newTestSessionMap(t, &CanonicalConfig{})
+
+	speakers := &testSession{key: "speakers [realtek]"}
+	if !m.targetMatchesSession("Speakers [Realtek]", speakers) {
+		t.Error("expected a device name with brackets to match itself")
+	}
+
+	if !m.targetMatchesSession("Speakers [Realtek", &testSession{key: "speakers [realtek"}) {
+		t.Error("expected a name that isn't a valid glob to match itself")
+	}
+
+	if m.targetMatchesSession("Speakers [Realtek]", &testSession{key: "speakers"}) {
+		t.Error("expected the bracketed name not to match other sessions")
+	}
+}
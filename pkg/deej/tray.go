@@ -1,6 +1,8 @@
 package deej
 
 import (
+	"sync"
+
 	"github.com/getlantern/systray"
 
 	"github.com/omriharel/deej/pkg/deej/icon"
@@ -23,6 +25,8 @@ func (d *Deej) initializeTray(onDone func()) {
 		refreshSessions := systray.AddMenuItem("Re-scan audio sessions", "Manually refresh audio sessions if something's stuck")
 		refreshSessions.SetIcon(icon.RefreshSessions)
 
+		d.initializeTrayScenes()
+
 		if d.version != "" {
 			systray.AddSeparator()
 			versionInfo := systray.AddMenuItem(d.version, "")
@@ -84,3 +88,53 @@ func (d *Deej) stopTray() {
 	d.logger.Debug("Quitting tray")
 	systray.Quit()
 }
+
+// trayScenes keeps the tray's "Restore scene" submenu in sync with the saved scenes
+type trayScenes struct {
+	menu  *systray.MenuItem
+	names map[string]bool
+	lock  sync.Mutex
+}
+
+func (d *Deej) initializeTrayScenes() {
+	d.trayScenes = &trayScenes{
+		menu:  systray.AddMenuItem("Restore scene", "Restore a saved volume scene"),
+		names: map[string]bool{},
+	}
+
+	d.trayScenes.menu.Disable()
+
+	for _, name := range d.config.sceneNames() {
+		d.addTrayScene(name)
+	}
+}
+
+// addTrayScene adds a scene to the tray's "Restore scene" submenu, unless it's already there (or there's no tray)
+func (d *Deej) addTrayScene(name string) {
+	if d.trayScenes == nil {
+		return
+	}
+
+	d.trayScenes.lock.Lock()
+	defer d.trayScenes.lock.Unlock()
+
+	if d.trayScenes.names[name] {
+		return
+	}
+
+	d.trayScenes.names[name] = true
+	d.trayScenes.menu.Enable()
+
+	item := d.trayScenes.menu.AddSubMenuItem(name, "")
+	logger := d.logger.Named("tray")
+
+	go func() {
+		for range item.ClickedCh {
+			logger.Infow("Scene menu item clicked, restoring scene", "scene", name)
+
+			if err := d.sessions.restoreScene(name); err != nil {
+				logger.Warnw("Failed to restore scene", "scene", name, "error", err)
+			}
+		}
+	}()
+}
@@ -0,0 +1,191 @@
+package deej
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// uinputKeyboard is a virtual keyboard created through /dev/uinput. keys sent through it come from the kernel
+// like any real keyboard's, so they work the same under X11 and Wayland
+type uinputKeyboard struct {
+	file *os.File
+}
+
+const (
+	uinputPath       = "/dev/uinput"
+	uinputDeviceName = "deej virtual keyboard"
+
+	// ioctls from linux/uinput.h
+	uinputSetEvBit   = 0x40045564 // UI_SET_EVBIT
+	uinputSetKeyBit  = 0x40045565 // UI_SET_KEYBIT
+	uinputDevCreate  = 0x5501     // UI_DEV_CREATE
+	uinputDevDestroy = 0x5502     // UI_DEV_DESTROY
+
+	// event types and codes from linux/input-event-codes.h
+	inputEventSyn   = 0x00
+	inputEventKey   = 0x01
+	inputSynReport  = 0
+	inputKeyRelease = 0
+	inputKeyPress   = 1
+
+	// the desktop needs a moment to pick up a new input device, and drops whatever it sends before that
+	uinputSettleDelay = 200 * time.Millisecond
+)
+
+// linux key codes, by modifier and key name
+var uinputKeyCodes = map[string]uint16{
+	keyModifierCtrl: 29, keyModifierShift: 42, keyModifierAlt: 56, keyModifierSuper: 125,
+
+	"esc": 1, "1": 2, "2": 3, "3": 4, "4": 5, "5": 6, "6": 7, "7": 8, "8": 9, "9": 10, "0": 11,
+	"minus": 12, "equal": 13, "backspace": 14, "tab": 15,
+	"q": 16, "w": 17, "e": 18, "r": 19, "t": 20, "y": 21, "u": 22, "i": 23, "o": 24, "p": 25,
+	"leftbracket": 26, "rightbracket": 27, "enter": 28,
+	"a": 30, "s": 31, "d": 32, "f": 33, "g": 34, "h": 35, "j": 36, "k": 37, "l": 38,
+	"semicolon": 39, "apostrophe": 40, "grave": 41, "backslash": 43,
+	"z": 44, "x": 45, "c": 46, "v": 47, "b": 48, "n": 49, "m": 50,
+	"comma": 51, "period": 52, "slash": 53, "space": 57, "capslock": 58,
+	"f1": 59, "f2": 60, "f3": 61, "f4": 62, "f5": 63, "f6": 64, "f7": 65, "f8": 66, "f9": 67, "f10": 68,
+	"numlock": 69, "scrolllock": 70, "f11": 87, "f12": 88, "printscreen": 99,
+	"home": 102, "up": 103, "pageup": 104, "left": 105, "right": 106, "end": 107, "down": 108,
+	"pagedown": 109, "insert": 110, "delete": 111,
+	"mute": 113, "volumedown": 114, "volumeup": 115, "pause": 119, "menu": 127,
+	"nexttrack": 163, "playpause": 164, "prevtrack": 165, "stop": 166,
+	"f13": 183, "f14": 184, "f15": 185, "f16": 186, "f17": 187, "f18": 188, "f19": 189, "f20": 190,
+	"f21": 191, "f22": 192, "f23": 193, "f24": 194,
+	"brightnessdown": 224, "brightnessup": 225,
+}
+
+// inputEvent matches struct input_event
+type inputEvent struct {
+	time      syscall.Timeval
+	eventType uint16
+	code      uint16
+	value     int32
+}
+
+// uinputUserDev matches struct uinput_user_dev, the legacy (but universally supported) way of setting a device up
+type uinputUserDev struct {
+	name      [80]byte
+	busType   uint16
+	vendor    uint16
+	product   uint16
+	version   uint16
+	ffEffects uint32
+	absMax    [64]int32
+	absMin    [64]int32
+	absFuzz   [64]int32
+	absFlat   [64]int32
+}
+
+// newUinputKeyboard creates the virtual keyboard. that usually takes root, or a udev rule granting access
+// to /dev/uinput (see the readme)
+func newUinputKeyboard() (*uinputKeyboard, error) {
+	file, err := os.OpenFile(uinputPath, os.O_WRONLY|syscall.O_NONBLOCK, 0)
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", uinputPath, err)
+	}
+
+	kb := &uinputKeyboard{file: file}
+
+	if err := kb.ioctl(uinputSetEvBit, inputEventKey); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("enable key events: %w", err)
+	}
+
+	for _, code := range uinputKeyCodes {
+		if err := kb.ioctl(uinputSetKeyBit, uintptr(code)); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("enable key %d: %w", code, err)
+		}
+	}
+
+	dev := uinputUserDev{
+		busType: 0x06, // BUS_VIRTUAL
+		vendor:  0x1d6b,
+		product: 0xdee1,
+		version: 1,
+	}
+
+	copy(dev.name[:], uinputDeviceName)
+
+	if _, err := file.Write((*[unsafe.Sizeof(dev)]byte)(unsafe.Pointer(&dev))[:]); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("write device setup: %w", err)
+	}
+
+	if err := kb.ioctl(uinputDevCreate, 0); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("create device: %w", err)
+	}
+
+	time.Sleep(uinputSettleDelay)
+
+	return kb, nil
+}
+
+// sendKeyCombo presses the combo's modifiers and key, and then lets go of them in reverse order
+func (kb *uinputKeyboard) sendKeyCombo(combo *KeyCombo) error {
+	codes := make([]uint16, 0, len(combo.Modifiers)+1)
+
+	for _, name := range append(append([]string{}, combo.Modifiers...), combo.Key) {
+		code, ok := uinputKeyCodes[name]
+		if !ok {
+			return fmt.Errorf("no key code for %s", name)
+		}
+
+		codes = append(codes, code)
+	}
+
+	for _, code := range codes {
+		if err := kb.emitKey(code, inputKeyPress); err != nil {
+			return err
+		}
+	}
+
+	for codeIdx := len(codes) - 1; codeIdx >= 0; codeIdx-- {
+		if err := kb.emitKey(codes[codeIdx], inputKeyRelease); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// emitKey presses or releases a key. each one gets its own report, so modifiers are registered before the key
+func (kb *uinputKeyboard) emitKey(code uint16, value int32) error {
+	if err := kb.emit(inputEventKey, code, value); err != nil {
+		return err
+	}
+
+	return kb.emit(inputEventSyn, inputSynReport, 0)
+}
+
+func (kb *uinputKeyboard) emit(eventType uint16, code uint16, value int32) error {
+	event := inputEvent{
+		eventType: eventType,
+		code:      code,
+		value:     value,
+	}
+
+	if _, err := kb.file.Write((*[unsafe.Sizeof(event)]byte)(unsafe.Pointer(&event))[:]); err != nil {
+		return fmt.Errorf("write input event: %w", err)
+	}
+
+	return nil
+}
+
+func (kb *uinputKeyboard) ioctl(request uintptr, arg uintptr) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, kb.file.Fd(), request, arg); errno != 0 {
+		return errno
+	}
+
+	return nil
+}
+
+func (kb *uinputKeyboard) close() error {
+	kb.ioctl(uinputDevDestroy, 0)
+	return kb.file.Close()
+}
@@ -39,7 +39,8 @@ func Linux() bool {
 // SetupCloseHandler creates a 'listener' on a new goroutine which will notify the
 // program if it receives an interrupt from the OS
 func SetupCloseHandler() chan os.Signal {
-	c := make(chan os.Signal)
+	// signal.Notify doesn't block when sending, so an unbuffered channel could miss the signal
+	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
 
 	return c
@@ -47,7 +48,7 @@ func SetupCloseHandler() chan os.Signal {
 
 // GetCurrentWindowProcessNames returns the process names (including extension, if applicable)
 // of the current foreground window. This includes child processes belonging to the window.
-// This is currently only implemented for Windows
+// On Linux, this follows the EWMH hints of the X server named by $DISPLAY
 func GetCurrentWindowProcessNames() ([]string, error) {
 	return getCurrentWindowProcessNames()
 }
@@ -1,9 +1,130 @@
 package util
 
 import (
-	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/mitchellh/go-ps"
+)
+
+const (
+	getCurrentWindowInternalCooldown = time.Millisecond * 350
+)
+
+var (
+	lastGetCurrentWindowResult []string
+	lastGetCurrentWindowCall   time.Time
+
+	// the X connection is kept open between calls, and re-established whenever it breaks
+	currentWindowConn     *x11Conn
+	currentWindowConnLock sync.Mutex
 )
 
 func getCurrentWindowProcessNames() ([]string, error) {
-	return nil, errors.New("Not implemented")
+	currentWindowConnLock.Lock()
+	defer currentWindowConnLock.Unlock()
+
+	// apply an internal cooldown on this function to avoid querying the X server too frequently.
+	// return a cached value during that cooldown
+	now := time.Now()
+	if lastGetCurrentWindowCall.Add(getCurrentWindowInternalCooldown).After(now) {
+		return lastGetCurrentWindowResult, nil
+	}
+
+	lastGetCurrentWindowCall = now
+
+	if currentWindowConn == nil {
+		conn, err := dialX11()
+		if err != nil {
+			return nil, fmt.Errorf("connect to X server: %w", err)
+		}
+
+		currentWindowConn = conn
+	}
+
+	ownerPID, err := getActiveWindowPID(currentWindowConn)
+	if err != nil {
+
+		// drop the connection so the next call starts fresh (the X server might have restarted)
+		currentWindowConn.close()
+		currentWindowConn = nil
+
+		return nil, fmt.Errorf("get active window pid: %w", err)
+	}
+
+	// no active window, or one that doesn't tell us its PID
+	if ownerPID == 0 {
+		lastGetCurrentWindowResult = nil
+		return nil, nil
+	}
+
+	// much like the windows implementation, the window's owner isn't necessarily the process playing audio.
+	// launchers (steam, wine, shell scripts) and multi-process apps keep their audio in a child process,
+	// so return the whole process tree below the window's owner and let the session map pick from that
+	processes, err := ps.Processes()
+	if err != nil {
+		return nil, fmt.Errorf("list processes: %w", err)
+	}
+
+	children := map[int][]int{}
+	for _, process := range processes {
+		children[process.PPid()] = append(children[process.PPid()], process.Pid())
+	}
+
+	result := []string{}
+	pending := []int{int(ownerPID)}
+
+	for len(pending) > 0 {
+		pid := pending[0]
+		pending = append(pending[1:], children[pid]...)
+
+		if name, ok := processBinaryName(pid); ok {
+			result = append(result, name)
+		}
+	}
+
+	// cache & return whichever executable names we ended up with
+	lastGetCurrentWindowResult = result
+	return result, nil
+}
+
+// getActiveWindowPID follows the EWMH hints to find which process owns the focused window
+func getActiveWindowPID(x *x11Conn) (uint32, error) {
+	window, ok, err := x.property32(x.root, "_NET_ACTIVE_WINDOW", x11AtomWindow)
+	if err != nil {
+		return 0, fmt.Errorf("get _NET_ACTIVE_WINDOW: %w", err)
+	}
+
+	if !ok || window == 0 {
+		return 0, nil
+	}
+
+	pid, ok, err := x.property32(window, "_NET_WM_PID", x11AtomCardinal)
+	if err != nil {
+		return 0, fmt.Errorf("get _NET_WM_PID: %w", err)
+	}
+
+	if !ok {
+		return 0, nil
+	}
+
+	return pid, nil
+}
+
+// processBinaryName returns the name PulseAudio would report as a process's binary: the base name of
+// its executable. the process name from /proc/<pid>/stat is only a fallback, as it gets cut at 15 characters
+func processBinaryName(pid int) (string, bool) {
+	if executable, err := os.Readlink(fmt.Sprintf("/proc/%d/exe", pid)); err == nil {
+		return filepath.Base(executable), true
+	}
+
+	process, err := ps.FindProcess(pid)
+	if err != nil || process == nil {
+		return "", false
+	}
+
+	return process.Executable(), true
 }
@@ -2,6 +2,7 @@ package util
 
 import (
 	"fmt"
+	"sync"
 	"syscall"
 	"time"
 	"unsafe"
@@ -17,9 +18,14 @@ const (
 var (
 	lastGetCurrentWindowResult []string
 	lastGetCurrentWindowCall   = time.Now()
+
+	// foreground mappings ask for the current window from several goroutines at once
+	getCurrentWindowLock sync.Mutex
 )
 
 func getCurrentWindowProcessNames() ([]string, error) {
+	getCurrentWindowLock.Lock()
+	defer getCurrentWindowLock.Unlock()
 
 	// apply an internal cooldown on this function to avoid calling windows API functions too frequently.
 	// return a cached value during that cooldown
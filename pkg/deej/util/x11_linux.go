@@ -0,0 +1,348 @@
+package util
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// x11Conn is a deliberately tiny X11 client: it only knows how to look up atoms and read window properties,
+// which is all we need to follow EWMH hints. pulling in a full X binding for that felt like overkill
+type x11Conn struct {
+	conn net.Conn
+	root uint32
+
+	atoms map[string]uint32
+}
+
+const (
+	x11OpcodeInternAtom  = 16
+	x11OpcodeGetProperty = 20
+
+	x11AtomCardinal = 6
+	x11AtomWindow   = 33
+
+	x11ReplyError = 0
+	x11ReplyOK    = 1
+
+	x11AuthName = "MIT-MAGIC-COOKIE-1"
+
+	// how long to wait on the X server before giving up on it. the connection is shared behind a lock,
+	// so a server that stops answering mustn't be able to hold everyone else up
+	x11Timeout = 2 * time.Second
+
+	// families as they appear in .Xauthority
+	xauthFamilyLocal    = 256
+	xauthFamilyWildcard = 65535
+)
+
+var errNoDisplay = errors.New("DISPLAY is not set")
+
+// dialX11 connects to the X server named by $DISPLAY (i.e. ":0", ":1.0" or "host:0")
+func dialX11() (*x11Conn, error) {
+	display := os.Getenv("DISPLAY")
+	if display == "" {
+		return nil, errNoDisplay
+	}
+
+	colonIdx := strings.LastIndex(display, ":")
+	if colonIdx < 0 {
+		return nil, fmt.Errorf("parse DISPLAY %q: missing display number", display)
+	}
+
+	host := display[:colonIdx]
+	displayNumber := display[colonIdx+1:]
+
+	// drop the screen number, if any
+	if dotIdx := strings.Index(displayNumber, "."); dotIdx >= 0 {
+		displayNumber = displayNumber[:dotIdx]
+	}
+
+	number, err := strconv.Atoi(displayNumber)
+	if err != nil {
+		return nil, fmt.Errorf("parse DISPLAY %q: %w", display, err)
+	}
+
+	var conn net.Conn
+
+	if host == "" || host == "unix" {
+		conn, err = net.DialTimeout("unix", fmt.Sprintf("/tmp/.X11-unix/X%d", number), x11Timeout)
+	} else {
+		conn, err = net.DialTimeout("tcp", net.JoinHostPort(host, strconv.Itoa(6000+number)), x11Timeout)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("connect to X server: %w", err)
+	}
+
+	x := &x11Conn{
+		conn:  conn,
+		atoms: map[string]uint32{},
+	}
+
+	if err := x.setup(displayNumber); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("set up X connection: %w", err)
+	}
+
+	return x, nil
+}
+
+func (x *x11Conn) close() error {
+	return x.conn.Close()
+}
+
+func (x *x11Conn) setup(displayNumber string) error {
+	authName, authData := readXauthority(displayNumber)
+
+	request := []byte{'l', 0}
+	request = appendUint16(request, 11) // protocol major version
+	request = appendUint16(request, 0)  // protocol minor version
+	request = appendUint16(request, uint16(len(authName)))
+	request = appendUint16(request, uint16(len(authData)))
+	request = append(request, 0, 0)
+	request = appendPadded(request, []byte(authName))
+	request = appendPadded(request, authData)
+
+	if err := x.conn.SetDeadline(time.Now().Add(x11Timeout)); err != nil {
+		return fmt.Errorf("set deadline: %w", err)
+	}
+
+	if _, err := x.conn.Write(request); err != nil {
+		return fmt.Errorf("write setup request: %w", err)
+	}
+
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(x.conn, header); err != nil {
+		return fmt.Errorf("read setup reply: %w", err)
+	}
+
+	body := make([]byte, int(binary.LittleEndian.Uint16(header[6:]))*4)
+	if _, err := io.ReadFull(x.conn, body); err != nil {
+		return fmt.Errorf("read setup reply: %w", err)
+	}
+
+	if header[0] != x11ReplyOK {
+		reasonLength := int(header[1])
+		if reasonLength > len(body) {
+			reasonLength = len(body)
+		}
+
+		return fmt.Errorf("X server refused connection: %s", body[:reasonLength])
+	}
+
+	// the fixed part of the reply is followed by the vendor string and pixmap formats, then the screens.
+	// all we want is the first screen's root window
+	const fixedLength = 32
+
+	if len(body) < fixedLength {
+		return errors.New("setup reply too short")
+	}
+
+	vendorLength := int(binary.LittleEndian.Uint16(body[16:]))
+	numFormats := int(body[21])
+
+	rootOffset := fixedLength + pad(vendorLength) + numFormats*8
+	if len(body) < rootOffset+4 {
+		return errors.New("setup reply too short")
+	}
+
+	x.root = binary.LittleEndian.Uint32(body[rootOffset:])
+
+	return nil
+}
+
+// atom returns the atom for the given name, or 0 if no such atom exists (yet)
+func (x *x11Conn) atom(name string) (uint32, error) {
+	if atom, ok := x.atoms[name]; ok {
+		return atom, nil
+	}
+
+	request := []byte{x11OpcodeInternAtom, 1} // only if exists
+	request = appendUint16(request, uint16(2+pad(len(name))/4))
+	request = appendUint16(request, uint16(len(name)))
+	request = append(request, 0, 0)
+	request = appendPadded(request, []byte(name))
+
+	reply, err := x.roundTrip(request)
+	if err != nil {
+		return 0, fmt.Errorf("intern atom %s: %w", name, err)
+	}
+
+	// atoms that don't exist yet aren't cached, as the window manager might still be starting up and create them later
+	atom := binary.LittleEndian.Uint32(reply[8:])
+	if atom != 0 {
+		x.atoms[name] = atom
+	}
+
+	return atom, nil
+}
+
+// property32 reads the first 32-bit value of a window property, returning false if it isn't set
+func (x *x11Conn) property32(window uint32, name string, propertyType uint32) (uint32, bool, error) {
+	atom, err := x.atom(name)
+	if err != nil {
+		return 0, false, err
+	}
+
+	if atom == 0 {
+		return 0, false, nil
+	}
+
+	request := []byte{x11OpcodeGetProperty, 0} // don't delete
+	request = appendUint16(request, 6)
+	request = appendUint32(request, window)
+	request = appendUint32(request, atom)
+	request = appendUint32(request, propertyType)
+	request = appendUint32(request, 0) // offset
+	request = appendUint32(request, 1) // length, in 32-bit units
+
+	reply, err := x.roundTrip(request)
+	if err != nil {
+		return 0, false, fmt.Errorf("get property %s: %w", name, err)
+	}
+
+	format := reply[1]
+	valueLength := binary.LittleEndian.Uint32(reply[16:])
+
+	if format != 32 || valueLength == 0 || len(reply) < 36 {
+		return 0, false, nil
+	}
+
+	return binary.LittleEndian.Uint32(reply[32:]), true, nil
+}
+
+// roundTrip sends a request and waits for its reply, skipping over any events the server sends meanwhile.
+// it gives up after x11Timeout, after which the connection shouldn't be used anymore
+func (x *x11Conn) roundTrip(request []byte) ([]byte, error) {
+	if err := x.conn.SetDeadline(time.Now().Add(x11Timeout)); err != nil {
+		return nil, fmt.Errorf("set deadline: %w", err)
+	}
+
+	if _, err := x.conn.Write(request); err != nil {
+		return nil, fmt.Errorf("write request: %w", err)
+	}
+
+	for {
+		reply := make([]byte, 32)
+		if _, err := io.ReadFull(x.conn, reply); err != nil {
+			return nil, fmt.Errorf("read reply: %w", err)
+		}
+
+		switch reply[0] {
+		case x11ReplyError:
+			return nil, fmt.Errorf("X error code %d", reply[1])
+
+		case x11ReplyOK:
+			extraLength := binary.LittleEndian.Uint32(reply[4:]) * 4
+			if extraLength > 0 {
+				extra := make([]byte, extraLength)
+				if _, err := io.ReadFull(x.conn, extra); err != nil {
+					return nil, fmt.Errorf("read reply: %w", err)
+				}
+
+				reply = append(reply, extra...)
+			}
+
+			return reply, nil
+		}
+	}
+}
+
+// readXauthority looks up the MIT-MAGIC-COOKIE-1 for our display. servers without access control (i.e. Xvfb)
+// don't need one, so any failure here just means we try connecting without credentials
+func readXauthority(displayNumber string) (string, []byte) {
+	path := os.Getenv("XAUTHORITY")
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", nil
+		}
+
+		path = filepath.Join(home, ".Xauthority")
+	}
+
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", nil
+	}
+
+	hostname, _ := os.Hostname()
+	reader := bytes.NewReader(contents)
+
+	readField := func() ([]byte, error) {
+		var length uint16
+		if err := binary.Read(reader, binary.BigEndian, &length); err != nil {
+			return nil, err
+		}
+
+		field := make([]byte, length)
+		_, err := io.ReadFull(reader, field)
+
+		return field, err
+	}
+
+	for {
+		var family uint16
+		if err := binary.Read(reader, binary.BigEndian, &family); err != nil {
+			return "", nil
+		}
+
+		address, err := readField()
+		if err != nil {
+			return "", nil
+		}
+
+		number, err := readField()
+		if err != nil {
+			return "", nil
+		}
+
+		name, err := readField()
+		if err != nil {
+			return "", nil
+		}
+
+		data, err := readField()
+		if err != nil {
+			return "", nil
+		}
+
+		if family != xauthFamilyWildcard && (family != xauthFamilyLocal || string(address) != hostname) {
+			continue
+		}
+
+		if string(number) != displayNumber || string(name) != x11AuthName {
+			continue
+		}
+
+		return string(name), data
+	}
+}
+
+func appendUint16(b []byte, v uint16) []byte {
+	return append(b, byte(v), byte(v>>8))
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	return append(b, byte(v), byte(v>>8), byte(v>>16), byte(v>>24))
+}
+
+func appendPadded(b []byte, data []byte) []byte {
+	b = append(b, data...)
+	return append(b, make([]byte, pad(len(data))-len(data))...)
+}
+
+// pad rounds n up to the next multiple of 4, as the protocol requires
+func pad(n int) int {
+	return (n + 3) &^ 3
+}
@@ -0,0 +1,366 @@
+package util
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	x11OpcodeChangeProperty = 18
+
+	// the X server sends PropertyNotify events whenever a property changes, which replies have to be told apart from
+	x11EventPropertyNotify = 28
+)
+
+func TestX11RoundTripTimesOut(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	// a server that takes requests and never answers them
+	go io.Copy(ioutil.Discard, server)
+
+	x := &x11Conn{conn: client, atoms: map[string]uint32{}}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := x.atom("_NET_ACTIVE_WINDOW")
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("expected the lookup to fail against a server that doesn't answer")
+		}
+
+	case <-time.After(x11Timeout + 5*time.Second):
+		t.Fatal("expected the lookup to give up once the timeout passed")
+	}
+}
+
+func TestX11ActiveWindow(t *testing.T) {
+	startFakeX11Server(t)
+	testActiveWindow(t)
+}
+
+func TestX11ActiveWindowWithXvfb(t *testing.T) {
+	startXvfb(t)
+	testActiveWindow(t)
+}
+
+// testActiveWindow plays window manager on whichever X server DISPLAY points at, and checks that deej follows along
+func testActiveWindow(t *testing.T) {
+	x, err := dialX11()
+	if err != nil {
+		t.Fatalf("connect to X server: %v", err)
+	}
+
+	defer x.close()
+
+	if x.root == 0 {
+		t.Fatal("expected a root window")
+	}
+
+	// there's no window manager yet, so nothing says which window is active
+	if pid, err := getActiveWindowPID(x); err != nil || pid != 0 {
+		t.Fatalf("expected no active window, got pid %d (error: %v)", pid, err)
+	}
+
+	// the window manager shows up: it makes the root window the active one, and claims it for this process.
+	// the connection deej had open from before has to pick that up
+	activeWindowAtom := internAtom(t, x, "_NET_ACTIVE_WINDOW")
+	pidAtom := internAtom(t, x, "_NET_WM_PID")
+
+	changeProperty32(t, x, x.root, activeWindowAtom, x11AtomWindow, x.root)
+	changeProperty32(t, x, x.root, pidAtom, x11AtomCardinal, uint32(os.Getpid()))
+
+	pid, err := getActiveWindowPID(x)
+	if err != nil {
+		t.Fatalf("get active window pid: %v", err)
+	}
+
+	if int(pid) != os.Getpid() {
+		t.Fatalf("expected pid %d, got %d", os.Getpid(), pid)
+	}
+
+	// and the same through the public path, which should name this test's own binary
+	currentWindowConnLock.Lock()
+	currentWindowConn = nil
+	lastGetCurrentWindowCall = time.Time{}
+	currentWindowConnLock.Unlock()
+
+	names, err := getCurrentWindowProcessNames()
+	if err != nil {
+		t.Fatalf("get current window process names: %v", err)
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		t.Fatalf("find test executable: %v", err)
+	}
+
+	if len(names) == 0 || names[0] != filepath.Base(executable) {
+		t.Errorf("expected names to start with %s, got %v", filepath.Base(executable), names)
+	}
+}
+
+// startXvfb runs an Xvfb on a free display and points DISPLAY at it for the rest of the test,
+// or skips the test if Xvfb isn't installed
+func startXvfb(t *testing.T) {
+	xvfbPath, err := exec.LookPath("Xvfb")
+	if err != nil {
+		t.Skip("Xvfb isn't installed")
+	}
+
+	number := freeDisplayNumber(t)
+
+	cmd := exec.Command(xvfbPath, fmt.Sprintf(":%d", number), "-nolisten", "tcp")
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("start Xvfb: %v", err)
+	}
+
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(50 * time.Millisecond) {
+		if _, err := os.Stat(fmt.Sprintf("/tmp/.X11-unix/X%d", number)); err == nil {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatal("Xvfb didn't come up in time")
+		}
+	}
+
+	useDisplay(t, number)
+}
+
+// fakeX11Server speaks just enough of the X protocol for deej's client: setup, InternAtom, GetProperty and ChangeProperty.
+// every reply is preceded by a PropertyNotify event, which the client has to skip over
+type fakeX11Server struct {
+	atoms      map[string]uint32
+	properties map[[2]uint32]uint32 // by window and property atom
+	lock       sync.Mutex
+}
+
+const fakeX11Root = 0x1ab
+
+// startFakeX11Server serves a fakeX11Server on a free display and points DISPLAY at it for the rest of the test
+func startFakeX11Server(t *testing.T) {
+	if err := os.MkdirAll("/tmp/.X11-unix", 01777); err != nil {
+		t.Skipf("can't create the X socket directory: %v", err)
+	}
+
+	number := freeDisplayNumber(t)
+
+	listener, err := net.Listen("unix", fmt.Sprintf("/tmp/.X11-unix/X%d", number))
+	if err != nil {
+		t.Fatalf("listen for X clients: %v", err)
+	}
+
+	t.Cleanup(func() {
+		listener.Close()
+	})
+
+	server := &fakeX11Server{
+		atoms:      map[string]uint32{},
+		properties: map[[2]uint32]uint32{},
+	}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go server.serve(conn)
+		}
+	}()
+
+	useDisplay(t, number)
+}
+
+func (s *fakeX11Server) serve(conn net.Conn) {
+	defer conn.Close()
+
+	// setup: a fixed header, then the (padded) authorization name and data, which anyone gets past
+	header := make([]byte, 12)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return
+	}
+
+	authLength := pad(int(binary.LittleEndian.Uint16(header[6:]))) + pad(int(binary.LittleEndian.Uint16(header[8:])))
+	if _, err := io.ReadFull(conn, make([]byte, authLength)); err != nil {
+		return
+	}
+
+	// no vendor string and no pixmap formats, so the first screen (starting with its root window) comes right away
+	body := make([]byte, 32+40)
+	binary.LittleEndian.PutUint32(body[32:], fakeX11Root)
+
+	reply := []byte{x11ReplyOK, 0}
+	reply = appendUint16(reply, 11)
+	reply = appendUint16(reply, 0)
+	reply = appendUint16(reply, uint16(len(body)/4))
+
+	if _, err := conn.Write(append(reply, body...)); err != nil {
+		return
+	}
+
+	for sequence := uint16(1); ; sequence++ {
+		requestHeader := make([]byte, 4)
+		if _, err := io.ReadFull(conn, requestHeader); err != nil {
+			return
+		}
+
+		request := make([]byte, int(binary.LittleEndian.Uint16(requestHeader[2:]))*4)
+		copy(request, requestHeader)
+
+		if _, err := io.ReadFull(conn, request[4:]); err != nil {
+			return
+		}
+
+		if reply := s.handle(request, sequence); reply != nil {
+			event := make([]byte, 32)
+			event[0] = x11EventPropertyNotify
+
+			if _, err := conn.Write(append(event, reply...)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// handle returns the reply to a request, or nil for requests without one
+func (s *fakeX11Server) handle(request []byte, sequence uint16) []byte {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	reply := make([]byte, 32)
+	reply[0] = x11ReplyOK
+	binary.LittleEndian.PutUint16(reply[2:], sequence)
+
+	switch request[0] {
+	case x11OpcodeInternAtom:
+		onlyIfExists := request[1] == 1
+		name := string(request[8 : 8+binary.LittleEndian.Uint16(request[4:])])
+
+		atom, ok := s.atoms[name]
+		if !ok && !onlyIfExists {
+			atom = uint32(100 + len(s.atoms))
+			s.atoms[name] = atom
+		}
+
+		binary.LittleEndian.PutUint32(reply[8:], atom)
+
+	case x11OpcodeGetProperty:
+		window := binary.LittleEndian.Uint32(request[4:])
+		property := binary.LittleEndian.Uint32(request[8:])
+
+		value, ok := s.properties[[2]uint32{window, property}]
+		if !ok {
+			return reply
+		}
+
+		reply[1] = 32
+		binary.LittleEndian.PutUint32(reply[4:], 1)                                        // extra length, in 32-bit units
+		binary.LittleEndian.PutUint32(reply[8:], binary.LittleEndian.Uint32(request[12:])) // type
+		binary.LittleEndian.PutUint32(reply[16:], 1)                                       // value length
+
+		return appendUint32(reply, value)
+
+	case x11OpcodeChangeProperty:
+		window := binary.LittleEndian.Uint32(request[4:])
+		property := binary.LittleEndian.Uint32(request[8:])
+
+		s.properties[[2]uint32{window, property}] = binary.LittleEndian.Uint32(request[24:])
+
+		return nil
+
+	default:
+		reply[0] = x11ReplyError
+		reply[1] = 1 // bad request
+	}
+
+	return reply
+}
+
+// freeDisplayNumber returns a display number nothing is listening on
+func freeDisplayNumber(t *testing.T) int {
+	for number := 90; number < 200; number++ {
+		if _, err := os.Stat(fmt.Sprintf("/tmp/.X11-unix/X%d", number)); os.IsNotExist(err) {
+			return number
+		}
+	}
+
+	t.Fatal("no free display number")
+	return 0
+}
+
+// useDisplay points DISPLAY at the given display until the test ends, and drops deej's connection afterwards
+func useDisplay(t *testing.T, number int) {
+	previousDisplay, hadDisplay := os.LookupEnv("DISPLAY")
+	os.Setenv("DISPLAY", fmt.Sprintf(":%d", number))
+
+	t.Cleanup(func() {
+		if hadDisplay {
+			os.Setenv("DISPLAY", previousDisplay)
+		} else {
+			os.Unsetenv("DISPLAY")
+		}
+
+		currentWindowConnLock.Lock()
+		defer currentWindowConnLock.Unlock()
+
+		if currentWindowConn != nil {
+			currentWindowConn.close()
+			currentWindowConn = nil
+		}
+	})
+}
+
+// internAtom creates an atom, unlike x11Conn.atom which only looks up existing ones
+func internAtom(t *testing.T, x *x11Conn, name string) uint32 {
+	request := []byte{x11OpcodeInternAtom, 0}
+	request = appendUint16(request, uint16(2+pad(len(name))/4))
+	request = appendUint16(request, uint16(len(name)))
+	request = append(request, 0, 0)
+	request = appendPadded(request, []byte(name))
+
+	reply, err := x.roundTrip(request)
+	if err != nil {
+		t.Fatalf("intern atom %s: %v", name, err)
+	}
+
+	return binary.LittleEndian.Uint32(reply[8:])
+}
+
+// changeProperty32 sets a window property to a single 32-bit value. the request has no reply,
+// but the server handles requests in order, so anything sent after it will see the new value
+func changeProperty32(t *testing.T, x *x11Conn, window, property, propertyType, value uint32) {
+	request := []byte{x11OpcodeChangeProperty, 0} // replace
+	request = appendUint16(request, 7)
+	request = appendUint32(request, window)
+	request = appendUint32(request, property)
+	request = appendUint32(request, propertyType)
+	request = append(request, 32, 0, 0, 0) // format, unused
+	request = appendUint32(request, 1)     // length, in 32-bit units
+	request = appendUint32(request, value)
+
+	if _, err := x.conn.Write(request); err != nil {
+		t.Fatalf("change property: %v", err)
+	}
+}